@@ -0,0 +1,52 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewClientCmd is the `gangplank client` command group, with NewStatusCmd
+// already attached; whatever assembles the root cobra.Command adds the
+// group itself.
+func NewClientCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "client",
+		Short: "Talk to a running gangplank daemon",
+	}
+	cmd.PersistentFlags().String("addr", "localhost:4343", "address of the gangplank daemon's RPC listener")
+	cmd.PersistentFlags().Bool("insecure", false, "skip TLS certificate verification when dialing the daemon")
+	cmd.AddCommand(NewStatusCmd())
+	return cmd
+}
+
+// NewStatusCmd implements `gangplank client status <jobID>`: it prints the
+// job's DAG, each stage indented under the stages its RequireArtifacts
+// depend on, with its live status.
+func NewStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status <jobID>",
+		Short: "Print a job's DAG with live status",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := cmd.Flags().GetString("addr")
+			if err != nil {
+				return err
+			}
+			insecure, err := cmd.Flags().GetBool("insecure")
+			if err != nil {
+				return err
+			}
+
+			c, err := Dial(addr, &tls.Config{InsecureSkipVerify: insecure}) //nolint:gosec
+			if err != nil {
+				return fmt.Errorf("failed to dial gangplank daemon at %s: %w", addr, err)
+			}
+			defer c.Close()
+
+			return PrintStatus(os.Stdout, args[0], c)
+		},
+	}
+}
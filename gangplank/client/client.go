@@ -0,0 +1,84 @@
+// Package client is the RPC client for the gangplank daemon. It is
+// deliberately thin: every method is a single net/rpc call, matching
+// the Service defined in daemon/rpc.go one-to-one.
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/rpc"
+
+	"github.com/coreos/gangplank/daemon"
+	"github.com/coreos/gangplank/spec"
+)
+
+// Client talks to a running gangplank daemon over a TLS-wrapped
+// net/rpc connection.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to a gangplank daemon listening at addr.
+func Dial(addr string, tlsConfig *tls.Config) (*Client, error) {
+	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gangplank daemon at %s: %w", addr, err)
+	}
+	return &Client{rpc: rpc.NewClient(conn)}, nil
+}
+
+// Close closes the underlying RPC connection.
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Submit submits a new job and returns its assigned ID.
+func (c *Client) Submit(js *spec.JobSpec, envVars []string, presentArtifacts []string) (string, error) {
+	args := &daemon.SubmitArgs{Spec: *js, EnvVars: envVars, PresentArtifacts: presentArtifacts}
+	var reply daemon.SubmitReply
+	if err := c.rpc.Call("Service.Submit", args, &reply); err != nil {
+		return "", err
+	}
+	return reply.JobID, nil
+}
+
+// Resume asks the daemon to resume a previously submitted job from its
+// persisted state, skipping stages already recorded as succeeded.
+func (c *Client) Resume(jobID string, envVars []string) error {
+	args := &daemon.ResumeArgs{JobID: jobID, EnvVars: envVars}
+	return c.rpc.Call("Service.Resume", args, &struct{}{})
+}
+
+// Status returns a job's current status, including every stage's state
+// recorded so far.
+func (c *Client) Status(jobID string) (*daemon.JobStatus, error) {
+	var reply daemon.JobStatus
+	if err := c.rpc.Call("Service.Status", &jobID, &reply); err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// Logs returns the captured output lines for a single stage.
+func (c *Client) Logs(jobID, stageID string) ([]string, error) {
+	args := &daemon.LogsArgs{JobID: jobID, StageID: stageID}
+	var reply []string
+	if err := c.rpc.Call("Service.Logs", args, &reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// Cancel requests that a running job be canceled.
+func (c *Client) Cancel(jobID string) error {
+	return c.rpc.Call("Service.Cancel", &jobID, &struct{}{})
+}
+
+// List returns every job known to the daemon.
+func (c *Client) List() ([]daemon.JobSummary, error) {
+	var reply []daemon.JobSummary
+	if err := c.rpc.Call("Service.List", &struct{}{}, &reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
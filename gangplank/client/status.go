@@ -0,0 +1,109 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/coreos/gangplank/daemon"
+)
+
+// PrintStatus renders a job's DAG with live status to w, indenting each
+// stage under the stages whose BuildArtifacts satisfy its RequireArtifacts
+// -- the same dependency rule spec.BuildDAG uses to schedule them -- so the
+// tree shape matches the order stages actually ran or will run in, not just
+// the flat list the daemon happened to persist them in.
+func PrintStatus(w io.Writer, jobID string, c *Client) error {
+	st, err := c.Status(jobID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch status for job %s: %w", jobID, err)
+	}
+
+	fmt.Fprintf(w, "job %s: %s\n", st.JobID, st.Status)
+	printStageTree(w, st.Stages)
+	return nil
+}
+
+// printStageTree indents each stage under every stage that produces one of
+// its RequireArtifacts, depth-first, root stages (none of whose
+// RequireArtifacts is built by another listed stage) first. A stage with more than one producer is printed under the first one
+// visited and cross-referenced under the rest, since plain indentation
+// can't draw a diamond.
+func printStageTree(w io.Writer, stages []daemon.StageStatus) {
+	byID := make(map[string]daemon.StageStatus, len(stages))
+	producers := make(map[string][]string) // artifact -> stage IDs that build it
+	for _, s := range stages {
+		byID[s.StageID] = s
+		for _, a := range s.BuildArtifacts {
+			producers[a] = append(producers[a], s.StageID)
+		}
+	}
+
+	parents := make(map[string][]string) // stageID -> producer stage IDs it depends on
+	children := make(map[string][]string)
+	for _, s := range stages {
+		seen := make(map[string]bool)
+		for _, ra := range s.RequireArtifacts {
+			for _, p := range producers[ra] {
+				if p == s.StageID || seen[p] {
+					continue
+				}
+				seen[p] = true
+				parents[s.StageID] = append(parents[s.StageID], p)
+				children[p] = append(children[p], s.StageID)
+			}
+		}
+	}
+
+	var roots []string
+	for _, s := range stages {
+		if len(parents[s.StageID]) == 0 {
+			roots = append(roots, s.StageID)
+		}
+	}
+	sort.Strings(roots)
+
+	printed := make(map[string]bool, len(stages))
+	var printNode func(id string, depth int)
+	printNode = func(id string, depth int) {
+		s := byID[id]
+		indent := ""
+		for i := 0; i < depth; i++ {
+			indent += "  "
+		}
+		line := fmt.Sprintf("%s[%s] %s", indent, s.Status, s.StageID)
+		if s.Error != "" {
+			line += fmt.Sprintf(" -- %s", s.Error)
+		}
+		if printed[id] {
+			fmt.Fprintf(w, "%s (see above)\n", line)
+			return
+		}
+		printed[id] = true
+		fmt.Fprintln(w, line)
+
+		kids := append([]string(nil), children[id]...)
+		sort.Strings(kids)
+		for _, k := range kids {
+			printNode(k, depth+1)
+		}
+	}
+
+	for _, id := range roots {
+		printNode(id, 0)
+	}
+
+	// A stage with an unsatisfiable RequireArtifacts has no producer and
+	// is printed as a root above; only a stage whose producers form a
+	// cycle is left unreached here. List it flat rather than drop it.
+	var orphans []string
+	for _, s := range stages {
+		if !printed[s.StageID] {
+			orphans = append(orphans, s.StageID)
+		}
+	}
+	sort.Strings(orphans)
+	for _, id := range orphans {
+		printNode(id, 0)
+	}
+}
@@ -0,0 +1,298 @@
+// Package daemon turns spec.JobSpec execution into a long-running
+// service: jobs are submitted once, run in the background, and survive
+// a daemon restart because every stage transition is written to an
+// embedded SQLite database (daemon/db) as it happens. A companion
+// client package (and the `gangplank client` subcommands) talk to it
+// over the RPC listener in rpc.go.
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/coreos/gangplank/daemon/db"
+	"github.com/coreos/gangplank/spec"
+)
+
+// Job status strings, persisted alongside each job's stage states.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// runningJob is the in-memory handle for a job the daemon is actively
+// executing, kept only so Cancel has something to call.
+type runningJob struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Daemon owns the DB and the set of jobs currently executing.
+//
+// A single RenderData is shared by every job the daemon runs. This
+// matters at the RPC boundary: RenderData carries the RendererExecuter
+// closure that actually runs a command, which cannot be marshaled
+// across the wire, so a submitting client never sends one -- it only
+// ever sends a JobSpec and the envVars for that job.
+type Daemon struct {
+	db *db.DB
+	rd *spec.RenderData
+
+	mu      sync.Mutex
+	running map[string]*runningJob
+
+	// sem bounds how many jobs may execute concurrently; Submit blocks
+	// a job's dispatch (not its RPC call) until a slot is free.
+	sem chan struct{}
+}
+
+// NewDaemon opens dbPath (creating it if needed) and returns a Daemon
+// that will run at most maxConcurrentJobs jobs at once, using rd to
+// execute every job's stages.
+func NewDaemon(dbPath string, maxConcurrentJobs int, rd *spec.RenderData) (*Daemon, error) {
+	if maxConcurrentJobs < 1 {
+		maxConcurrentJobs = 1
+	}
+	d, err := db.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Daemon{
+		db:      d,
+		rd:      rd,
+		running: make(map[string]*runningJob),
+		sem:     make(chan struct{}, maxConcurrentJobs),
+	}, nil
+}
+
+// Close releases the daemon's database handle. In-flight jobs are left
+// running; call Cancel on them first if that isn't desired.
+func (d *Daemon) Close() error {
+	return d.db.Close()
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job id: %w", err)
+	}
+	return "job-" + hex.EncodeToString(b), nil
+}
+
+// Submit persists js as a new job and starts executing it in the
+// background, returning immediately with the assigned job ID. envVars
+// is the environment for this job's commands; the daemon supplies its
+// own RenderData (set at NewDaemon time) to actually run them.
+func (d *Daemon) Submit(js *spec.JobSpec, envVars []string, presentArtifacts []string) (string, error) {
+	jobID, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	specJSON, err := json.Marshal(js)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize job spec: %w", err)
+	}
+	if err := d.db.CreateJob(jobID, string(specJSON), JobPending); err != nil {
+		return "", fmt.Errorf("failed to persist job %s: %w", jobID, err)
+	}
+
+	d.dispatch(jobID, js, envVars, presentArtifacts)
+	return jobID, nil
+}
+
+// Resume re-executes a previously submitted job, skipping any stage
+// that the DB already has recorded as StageSucceeded. This is how a job
+// picks back up after a daemon restart: the caller looks up jobIDs via
+// List, then calls Resume on any that never reached a terminal status.
+func (d *Daemon) Resume(jobID string, envVars []string) error {
+	rec, err := d.db.GetJob(jobID)
+	if err != nil {
+		return err
+	}
+
+	var js spec.JobSpec
+	if err := json.Unmarshal([]byte(rec.Spec), &js); err != nil {
+		return fmt.Errorf("failed to deserialize job spec for %s: %w", jobID, err)
+	}
+
+	stages, err := d.db.GetStages(jobID)
+	if err != nil {
+		return err
+	}
+	succeeded := make(map[string]bool, len(stages))
+	for _, s := range stages {
+		if s.Status == string(spec.StageSucceeded) {
+			succeeded[s.StageID] = true
+		}
+	}
+
+	var presentArtifacts []string
+	remaining := js.Stages[:0]
+	for _, s := range js.Stages {
+		if succeeded[s.ID] {
+			log.WithFields(log.Fields{"job": jobID, "stage": s.ID}).Info("resume: skipping already-succeeded stage")
+			presentArtifacts = append(presentArtifacts, s.BuildArtifacts...)
+			continue
+		}
+		remaining = append(remaining, s)
+	}
+	js.Stages = remaining
+
+	d.dispatch(jobID, &js, envVars, presentArtifacts)
+	return nil
+}
+
+// dispatch launches the goroutine that actually calls JobSpec.Execute,
+// blocking on the concurrency semaphore first so at most sem's
+// capacity worth of jobs run at once.
+func (d *Daemon) dispatch(jobID string, js *spec.JobSpec, envVars []string, presentArtifacts []string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rj := &runningJob{cancel: cancel, done: make(chan struct{})}
+
+	d.mu.Lock()
+	d.running[jobID] = rj
+	d.mu.Unlock()
+
+	go func() {
+		defer close(rj.done)
+		defer func() {
+			d.mu.Lock()
+			delete(d.running, jobID)
+			d.mu.Unlock()
+		}()
+
+		d.sem <- struct{}{}
+		defer func() { <-d.sem }()
+
+		_ = d.db.SetJobStatus(jobID, JobRunning)
+		reporter := newDBReporter(jobID, d.db)
+
+		err := js.Execute(ctx, d.rd, envVars, presentArtifacts, reporter)
+		switch {
+		case ctx.Err() == context.Canceled:
+			_ = d.db.SetJobStatus(jobID, JobCancelled)
+		case err != nil:
+			log.WithError(err).WithField("job", jobID).Error("job failed")
+			_ = d.db.SetJobStatus(jobID, JobFailed)
+		default:
+			_ = d.db.SetJobStatus(jobID, JobSucceeded)
+		}
+	}()
+}
+
+// Cancel requests that a running job's context be canceled. It returns
+// an error if the job is not currently executing in this daemon process
+// (e.g. it already finished, or belongs to a run before a restart).
+func (d *Daemon) Cancel(jobID string) error {
+	d.mu.Lock()
+	rj, ok := d.running[jobID]
+	d.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("job %s is not currently running", jobID)
+	}
+	rj.cancel()
+	return nil
+}
+
+// StageStatus is one stage's status as reported to a client.
+type StageStatus struct {
+	StageID    string     `json:"stage_id"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// RequireArtifacts and BuildArtifacts mirror the submitted spec.Stage's
+	// fields of the same name, so a client can rebuild the DAG's edges
+	// (same rule as spec.BuildDAG: a stage requiring an artifact depends on
+	// every stage that builds it) without re-submitting the JobSpec.
+	RequireArtifacts []string `json:"require_artifacts,omitempty"`
+	BuildArtifacts   []string `json:"build_artifacts,omitempty"`
+}
+
+// JobStatus is the full status of a job: its top-level state plus the
+// per-stage breakdown, enough for a client to render the DAG.
+type JobStatus struct {
+	JobID  string        `json:"job_id"`
+	Status string        `json:"status"`
+	Stages []StageStatus `json:"stages"`
+}
+
+// Status returns a job's current status, combining the job row with
+// every stage transition recorded so far.
+func (d *Daemon) Status(jobID string) (*JobStatus, error) {
+	rec, err := d.db.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	stages, err := d.db.GetStages(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The stages table only tracks transitions, not the dependency edges
+	// themselves; those still live in the submitted JobSpec, so decode it
+	// back out to label each stage with the RequireArtifacts/BuildArtifacts
+	// PrintStatus needs to draw the DAG rather than a flat list.
+	var js spec.JobSpec
+	if err := json.Unmarshal([]byte(rec.Spec), &js); err != nil {
+		return nil, fmt.Errorf("failed to decode stored spec for job %s: %w", jobID, err)
+	}
+	artifactsByStage := make(map[string]spec.Stage, len(js.Stages))
+	for _, s := range js.Stages {
+		artifactsByStage[s.ID] = s
+	}
+
+	out := &JobStatus{JobID: rec.ID, Status: rec.Status}
+	for _, s := range stages {
+		ss := StageStatus{
+			StageID:    s.StageID,
+			Status:     s.Status,
+			Error:      s.Error,
+			StartedAt:  s.StartedAt,
+			FinishedAt: s.FinishedAt,
+		}
+		if def, ok := artifactsByStage[s.StageID]; ok {
+			ss.RequireArtifacts = def.RequireArtifacts
+			ss.BuildArtifacts = def.BuildArtifacts
+		}
+		out.Stages = append(out.Stages, ss)
+	}
+	return out, nil
+}
+
+// JobSummary is one row of List's output.
+type JobSummary struct {
+	JobID     string    `json:"job_id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// List returns every known job, most recently submitted first.
+func (d *Daemon) List() ([]JobSummary, error) {
+	recs, err := d.db.ListJobs()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]JobSummary, 0, len(recs))
+	for _, r := range recs {
+		out = append(out, JobSummary{JobID: r.ID, Status: r.Status, CreatedAt: r.CreatedAt})
+	}
+	return out, nil
+}
+
+// Logs returns the captured output lines for a single stage.
+func (d *Daemon) Logs(jobID, stageID string) ([]string, error) {
+	return d.db.GetLogs(jobID, stageID)
+}
@@ -0,0 +1,219 @@
+// Package db is the SQLite-backed persistence layer for the gangplank
+// daemon. It exists so that a Daemon can restart without losing track
+// of in-flight jobs: every stage transition is written here as it
+// happens, and a resumed job is rebuilt by reading it back.
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	// SQLite driver, registered for "sqlite3" under database/sql.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// schema creates the jobs, stages, and logs tables if they don't
+// already exist. Kept as a single idempotent batch of statements so Open
+// can run it unconditionally on every startup.
+const schema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id         TEXT PRIMARY KEY,
+	spec       TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS stages (
+	job_id      TEXT NOT NULL,
+	stage_id    TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	error       TEXT,
+	started_at  DATETIME,
+	finished_at DATETIME,
+	PRIMARY KEY (job_id, stage_id)
+);
+
+CREATE TABLE IF NOT EXISTS stage_logs (
+	job_id    TEXT NOT NULL,
+	stage_id  TEXT NOT NULL,
+	attempt   INTEGER NOT NULL,
+	line      TEXT NOT NULL,
+	logged_at DATETIME NOT NULL
+);
+`
+
+// DB is a handle to the daemon's SQLite database.
+type DB struct {
+	sql *sql.DB
+}
+
+// Open opens (and, if needed, initializes) the SQLite database at path.
+func Open(path string) (*DB, error) {
+	sqlDB, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database %s: %w", path, err)
+	}
+	if _, err := sqlDB.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to initialize sqlite schema: %w", err)
+	}
+	return &DB{sql: sqlDB}, nil
+}
+
+// Close closes the underlying database handle.
+func (d *DB) Close() error {
+	return d.sql.Close()
+}
+
+// CreateJob records a new job with the given serialized spec.
+func (d *DB) CreateJob(jobID, specJSON, status string) error {
+	now := time.Now().UTC()
+	_, err := d.sql.Exec(
+		`INSERT INTO jobs (id, spec, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		jobID, specJSON, status, now, now,
+	)
+	return err
+}
+
+// SetJobStatus updates a job's top-level status (e.g. Running, Succeeded,
+// Failed, Cancelled).
+func (d *DB) SetJobStatus(jobID, status string) error {
+	_, err := d.sql.Exec(
+		`UPDATE jobs SET status = ?, updated_at = ? WHERE id = ?`,
+		status, time.Now().UTC(), jobID,
+	)
+	return err
+}
+
+// JobRecord is a persisted job row.
+type JobRecord struct {
+	ID        string
+	Spec      string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// GetJob returns a single job by ID.
+func (d *DB) GetJob(jobID string) (*JobRecord, error) {
+	row := d.sql.QueryRow(`SELECT id, spec, status, created_at, updated_at FROM jobs WHERE id = ?`, jobID)
+	var j JobRecord
+	if err := row.Scan(&j.ID, &j.Spec, &j.Status, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("no such job %q: %w", jobID, err)
+	}
+	return &j, nil
+}
+
+// ListJobs returns every known job, most recently created first.
+func (d *DB) ListJobs() ([]JobRecord, error) {
+	rows, err := d.sql.Query(`SELECT id, spec, status, created_at, updated_at FROM jobs ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []JobRecord
+	for rows.Next() {
+		var j JobRecord
+		if err := rows.Scan(&j.ID, &j.Spec, &j.Status, &j.CreatedAt, &j.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// StageRecord is a persisted stage transition row.
+type StageRecord struct {
+	StageID    string
+	Status     string
+	Error      string
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// UpsertStageStarted records that a stage began running.
+func (d *DB) UpsertStageStarted(jobID, stageID string) error {
+	now := time.Now().UTC()
+	_, err := d.sql.Exec(
+		`INSERT INTO stages (job_id, stage_id, status, started_at) VALUES (?, ?, 'running', ?)
+		 ON CONFLICT(job_id, stage_id) DO UPDATE SET status = 'running', started_at = excluded.started_at`,
+		jobID, stageID, now,
+	)
+	return err
+}
+
+// UpsertStageFinished records a stage's terminal status.
+func (d *DB) UpsertStageFinished(jobID, stageID, status, errMsg string) error {
+	now := time.Now().UTC()
+	_, err := d.sql.Exec(
+		`INSERT INTO stages (job_id, stage_id, status, error, finished_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(job_id, stage_id) DO UPDATE SET status = excluded.status, error = excluded.error, finished_at = excluded.finished_at`,
+		jobID, stageID, status, errMsg, now,
+	)
+	return err
+}
+
+// GetStages returns every stage transition recorded for a job.
+func (d *DB) GetStages(jobID string) ([]StageRecord, error) {
+	rows, err := d.sql.Query(
+		`SELECT stage_id, status, error, started_at, finished_at FROM stages WHERE job_id = ?`,
+		jobID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []StageRecord
+	for rows.Next() {
+		var r StageRecord
+		var errMsg sql.NullString
+		var started, finished sql.NullTime
+		if err := rows.Scan(&r.StageID, &r.Status, &errMsg, &started, &finished); err != nil {
+			return nil, err
+		}
+		r.Error = errMsg.String
+		if started.Valid {
+			r.StartedAt = &started.Time
+		}
+		if finished.Valid {
+			r.FinishedAt = &finished.Time
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// AppendLog records one line of a stage attempt's captured output.
+func (d *DB) AppendLog(jobID, stageID string, attempt int, line string) error {
+	_, err := d.sql.Exec(
+		`INSERT INTO stage_logs (job_id, stage_id, attempt, line, logged_at) VALUES (?, ?, ?, ?, ?)`,
+		jobID, stageID, attempt, line, time.Now().UTC(),
+	)
+	return err
+}
+
+// GetLogs returns every captured line for a stage, in the order they
+// were logged.
+func (d *DB) GetLogs(jobID, stageID string) ([]string, error) {
+	rows, err := d.sql.Query(
+		`SELECT line FROM stage_logs WHERE job_id = ? AND stage_id = ? ORDER BY rowid ASC`,
+		jobID, stageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		out = append(out, line)
+	}
+	return out, rows.Err()
+}
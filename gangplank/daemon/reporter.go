@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/coreos/gangplank/daemon/db"
+	"github.com/coreos/gangplank/spec"
+)
+
+// dbReporter implements spec.StageReporter by persisting every stage
+// transition and log line for a single job to the daemon's database.
+// One is constructed per running job, with jobID baked in, since
+// spec.StageReporter's methods are only keyed by stage ID.
+type dbReporter struct {
+	jobID string
+	db    *db.DB
+}
+
+var _ spec.StageReporter = &dbReporter{}
+
+func newDBReporter(jobID string, d *db.DB) *dbReporter {
+	return &dbReporter{jobID: jobID, db: d}
+}
+
+func (r *dbReporter) Started(stageID string) {
+	if err := r.db.UpsertStageStarted(r.jobID, stageID); err != nil {
+		log.WithError(err).WithFields(log.Fields{"job": r.jobID, "stage": stageID}).Error("failed to persist stage start")
+	}
+}
+
+func (r *dbReporter) Finished(stageID string, status spec.StageStatus, stageErr error) {
+	errMsg := ""
+	if stageErr != nil {
+		errMsg = stageErr.Error()
+	}
+	if err := r.db.UpsertStageFinished(r.jobID, stageID, string(status), errMsg); err != nil {
+		log.WithError(err).WithFields(log.Fields{"job": r.jobID, "stage": stageID}).Error("failed to persist stage completion")
+	}
+}
+
+func (r *dbReporter) Output(stageID string, attempt int, line string) {
+	if err := r.db.AppendLog(r.jobID, stageID, attempt, line); err != nil {
+		log.WithError(err).WithFields(log.Fields{"job": r.jobID, "stage": stageID}).Warn("failed to persist stage log line")
+	}
+}
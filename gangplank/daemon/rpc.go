@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/rpc"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/coreos/gangplank/spec"
+)
+
+// Service is the net/rpc-registered wrapper around a Daemon. net/rpc
+// requires exported methods of the shape func(args, *reply) error, so
+// it can't just be Daemon's own methods; Service adapts them.
+type Service struct {
+	d *Daemon
+}
+
+// SubmitArgs is the payload for Service.Submit. Note there is no
+// RenderData here: see the Daemon doc comment for why.
+type SubmitArgs struct {
+	Spec             spec.JobSpec
+	EnvVars          []string
+	PresentArtifacts []string
+}
+
+// SubmitReply carries back the assigned job ID.
+type SubmitReply struct {
+	JobID string
+}
+
+// Submit is the RPC entry point for Daemon.Submit.
+func (s *Service) Submit(args *SubmitArgs, reply *SubmitReply) error {
+	jobID, err := s.d.Submit(&args.Spec, args.EnvVars, args.PresentArtifacts)
+	if err != nil {
+		return err
+	}
+	reply.JobID = jobID
+	return nil
+}
+
+// ResumeArgs is the payload for Service.Resume.
+type ResumeArgs struct {
+	JobID   string
+	EnvVars []string
+}
+
+// Resume is the RPC entry point for Daemon.Resume.
+func (s *Service) Resume(args *ResumeArgs, reply *struct{}) error {
+	return s.d.Resume(args.JobID, args.EnvVars)
+}
+
+// Status is the RPC entry point for Daemon.Status.
+func (s *Service) Status(jobID *string, reply *JobStatus) error {
+	st, err := s.d.Status(*jobID)
+	if err != nil {
+		return err
+	}
+	*reply = *st
+	return nil
+}
+
+// LogsArgs is the payload for Service.Logs.
+type LogsArgs struct {
+	JobID   string
+	StageID string
+}
+
+// Logs is the RPC entry point for Daemon.Logs.
+func (s *Service) Logs(args *LogsArgs, reply *[]string) error {
+	lines, err := s.d.Logs(args.JobID, args.StageID)
+	if err != nil {
+		return err
+	}
+	*reply = lines
+	return nil
+}
+
+// Cancel is the RPC entry point for Daemon.Cancel.
+func (s *Service) Cancel(jobID *string, reply *struct{}) error {
+	return s.d.Cancel(*jobID)
+}
+
+// List is the RPC entry point for Daemon.List.
+func (s *Service) List(args *struct{}, reply *[]JobSummary) error {
+	jobs, err := s.d.List()
+	if err != nil {
+		return err
+	}
+	*reply = jobs
+	return nil
+}
+
+// ListenAndServe registers d's Service and serves net/rpc connections
+// on a TLS listener bound to addr until the listener is closed.
+func ListenAndServe(addr string, tlsConfig *tls.Config, d *Daemon) error {
+	if err := rpc.Register(&Service{d: d}); err != nil {
+		return fmt.Errorf("failed to register daemon RPC service: %w", err)
+	}
+
+	l, err := tls.Listen("tcp", addr, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	defer l.Close()
+
+	log.WithField("addr", addr).Info("gangplank daemon listening")
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			return fmt.Errorf("accept failed: %w", err)
+		}
+		go rpc.ServeConn(conn)
+	}
+}
@@ -2,6 +2,9 @@ package ocp
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -14,11 +17,11 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/containers/libpod/libpod"
-	"github.com/containers/libpod/libpod/define"
-	"github.com/containers/libpod/pkg/bindings"
-	"github.com/containers/libpod/pkg/bindings/containers"
-	"github.com/containers/libpod/pkg/specgen"
+	"github.com/containers/image/v5/docker"
+	imgtypes "github.com/containers/image/v5/types"
+	"github.com/containers/podman/v4/pkg/bindings"
+	"github.com/containers/podman/v4/pkg/bindings/containers"
+	"github.com/containers/podman/v4/pkg/specgen"
 	"github.com/containers/storage"
 	"github.com/containers/storage/pkg/idtools"
 	"github.com/opencontainers/runc/libcontainer/user"
@@ -30,12 +33,70 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 const (
 	kvmLabel       = "devices.kubevirt.io/kvm"
 	localPodEnvVar = "COSA_FORCE_NO_CLUSTER"
+
+	// checkpointAnnotationKey is patched onto the worker Pod by
+	// cosaPod.checkpoint once an archive has been exported. NewCosaPodder
+	// reads it back off the prior pod for this build/index to decide
+	// whether to resume instead of starting fresh.
+	checkpointAnnotationKey = "cosa.checkpoint/path"
+
+	// checkpointRequestAnnotationKey is patched onto the worker Pod by
+	// clusterRunner on SIGUSR1: clusterRunner never holds the local
+	// container it would need to checkpoint directly (the real container
+	// lives inside the Pod it created, not its own process), so this is
+	// the only lever it has -- ask whatever runs inside the Pod to
+	// checkpoint itself and patch checkpointAnnotationKey back when done.
+	checkpointRequestAnnotationKey = "cosa.checkpoint/requested"
+
+	// checkpointRestoreEnvVar tells the container getPodSpec builds where
+	// to restore a checkpoint archive from, mirroring cp.checkpointPath
+	// across the process boundary between clusterRunner (which looked the
+	// path up) and whatever runs inside the Pod it creates (which is the
+	// one with a local podman connection to actually restore with).
+	checkpointRestoreEnvVar = "COSA_CHECKPOINT_RESTORE_PATH"
+
+	// checkpointTimeout bounds how long a checkpoint is allowed to run
+	// before the SIGUSR1 handler gives up and falls through to a normal
+	// termination instead.
+	checkpointTimeout = 2 * time.Minute
+
+	// checkpointRestoreTimeout bounds how long a restore from a checkpoint
+	// archive is allowed to run before podmanRunner gives up and starts
+	// the worker fresh instead.
+	checkpointRestoreTimeout = 5 * time.Minute
+
+	// autoupdatePolicyAnnotationKey selects how, if at all, the worker
+	// image is auto-updated mid-build. Values mirror podman's
+	// io.containers.autoupdate labels.
+	autoupdatePolicyAnnotationKey = "cosa.autoupdate.policy"
+
+	// autoupdatePolicyDisabled preserves today's behavior: the worker
+	// image is pinned for the whole build. The default.
+	autoupdatePolicyDisabled = "disabled"
+	// autoupdatePolicyImage is reserved for a locally-available-image
+	// check, mirroring podman's "image" policy; not implemented here since
+	// the runners don't keep a local image cache to check against.
+	autoupdatePolicyImage = "image"
+	// autoupdatePolicyRegistry polls the registry for a newer digest of
+	// the worker image and swaps to it at the next stage boundary.
+	autoupdatePolicyRegistry = "registry"
+
+	// autoupdatePollInterval is how often watchForAutoUpdate re-checks the
+	// registry for a newer worker image digest.
+	autoupdatePollInterval = 5 * time.Minute
+
+	// stageLogPrefix begins the message spec.Stage.Execute logs at the
+	// start of every stage; streamPodLogs and podmanRunner's log scanner
+	// watch for it to find a safe point to swap the worker image
+	// mid-build. logrus puts its own time/level fields ahead of the
+	// message, so it is matched anywhere in a line, not as a line prefix.
+	stageLogPrefix = "Stage: "
 )
 
 var (
@@ -110,6 +171,30 @@ type cosaPod struct {
 
 	index int
 	pod   *v1.Pod
+
+	// arch pins the worker pod to a specific architecture via NodeSelector
+	// and Tolerations; empty means "whatever the cluster's default is."
+	// Set by MultiArchCosaPodder, unused by a plain cosaPod.
+	arch string
+
+	// checkpointPath is the CRIU archive to restore from at startup, read
+	// off the prior worker Pod's checkpointAnnotationKey annotation by
+	// NewCosaPodder. Empty means "start fresh." podmanRunner restores
+	// directly from it; clusterRunner has no local container of its own,
+	// so getPodSpec instead forwards it to the new Pod via
+	// checkpointRestoreEnvVar for whatever runs inside to restore from.
+	checkpointPath string
+
+	// autoupdatePolicy is one of the autoupdatePolicy* constants, read off
+	// apiBuild's autoupdatePolicyAnnotationKey annotation by NewCosaPodder.
+	// Defaults to autoupdatePolicyDisabled.
+	autoupdatePolicy string
+
+	// stageBoundary is signalled once per stage by streamPodLogs/the
+	// podmanRunner log scanner when the worker logs the start of a new
+	// stage. watchForAutoUpdate's consumers wait on it before swapping the
+	// worker image mid-build, so the swap never lands mid-stage.
+	stageBoundary chan struct{}
 }
 
 // CosaPodder create COSA capable pods.
@@ -138,9 +223,22 @@ func NewCosaPodder(
 
 		volumes:      volumes,
 		volumeMounts: volumeMounts,
+
+		autoupdatePolicy: autoupdatePolicyDisabled,
+		stageBoundary:    make(chan struct{}, 1),
+	}
+
+	switch policy := apiBuild.Annotations[autoupdatePolicyAnnotationKey]; policy {
+	case "", autoupdatePolicyDisabled:
+		// Already set above.
+	case autoupdatePolicyImage, autoupdatePolicyRegistry:
+		log.Infof("found %s annotation, worker image auto-update policy is %q", autoupdatePolicyAnnotationKey, policy)
+		cp.autoupdatePolicy = policy
+	default:
+		log.Warnf("unknown %s value %q, defaulting to %s", autoupdatePolicyAnnotationKey, policy, autoupdatePolicyDisabled)
 	}
 
-	ac, _, err := GetClient(ctx)
+	ac, ns, err := GetClient(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -172,23 +270,60 @@ func NewCosaPodder(
 		if err := cp.addVolumesFromConfigMapLabels(); err != nil {
 			return nil, fmt.Errorf("failed to add configMap volumes and mountsi: %w", err)
 		}
+
+		// A prior worker for this same build/index may have checkpointed
+		// itself before being evicted; its annotation lives on the Pod it
+		// patched in cp.checkpoint, not on the Build, so look the Pod up by
+		// the same deterministic name rather than trusting in-memory state.
+		// ac != nil here means clusterRunner will be the one dispatched to
+		// for this build, so cp.checkpointPath is consumed via getPodSpec's
+		// checkpointRestoreEnvVar rather than a local restore.
+		prior, perr := ac.CoreV1().Pods(ns).Get(cp.podName(), metav1.GetOptions{})
+		if perr != nil {
+			log.WithError(perr).Infof("no prior worker pod %s found, starting fresh", cp.podName())
+		} else if path, ok := prior.Annotations[checkpointAnnotationKey]; ok && path != "" {
+			log.Infof("found %s annotation on pod %s, will attempt restore from %s", checkpointAnnotationKey, prior.Name, path)
+			cp.checkpointPath = path
+		}
 	}
 
 	return cp, nil
 }
 
+// podName is the deterministic name of the worker pod for this build and
+// index, suffixed with cp.arch when set so that MultiArchCosaPodder's
+// per-arch pods don't collide. It is shared by getPodSpec (to create it),
+// checkpoint (to annotate it), and NewCosaPodder (to look up a prior
+// checkpoint annotation on it); NewCosaPodder runs before
+// MultiArchCosaPodder sets arch, so its lookup uses the unsuffixed name.
+func (cp *cosaPod) podName() string {
+	name := fmt.Sprintf("%s-%s-worker-%d",
+		cp.apiBuild.Annotations[buildapiv1.BuildConfigAnnotation],
+		cp.apiBuild.Annotations[buildapiv1.BuildNumberAnnotation],
+		cp.index,
+	)
+	if cp.arch != "" {
+		name = fmt.Sprintf("%s-%s", name, cp.arch)
+	}
+	return name
+}
+
 func ptrInt(i int64) *int64 { return &i }
 func ptrBool(b bool) *bool  { return &b }
 
 // getPodSpec returns a pod specification.
 func (cp *cosaPod) getPodSpec(envVars []v1.EnvVar) *v1.Pod {
-	podName := fmt.Sprintf("%s-%s-worker-%d",
-		cp.apiBuild.Annotations[buildapiv1.BuildConfigAnnotation],
-		cp.apiBuild.Annotations[buildapiv1.BuildNumberAnnotation],
-		cp.index,
-	)
+	podName := cp.podName()
 	log.Infof("Creating pod %s", podName)
 
+	if cp.checkpointPath != "" {
+		// Tell whatever runs inside this Pod where to restore from; the
+		// archive itself lives under srvDir (a PVC when
+		// clusterCtx.podmanSrvDir is set), so it is reachable by a new Pod
+		// the same way it was by the one that wrote it.
+		envVars = append(envVars, v1.EnvVar{Name: checkpointRestoreEnvVar, Value: cp.checkpointPath})
+	}
+
 	cosaBasePod := v1.Container{
 		Name:  podName,
 		Image: apiBuild.Spec.Strategy.CustomStrategy.From.Name,
@@ -223,7 +358,7 @@ export PATH=/usr/sbin:/usr/bin
 		cosaInit = []v1.Container{*initCtr}
 	}
 
-	return &v1.Pod{
+	pod := &v1.Pod{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Pod",
 			APIVersion: "v1",
@@ -245,6 +380,20 @@ export PATH=/usr/sbin:/usr/bin
 			Volumes:                       cp.volumes,
 		},
 	}
+
+	if cp.arch != "" {
+		pod.Spec.NodeSelector = map[string]string{archNodeSelectorLabel: cp.arch}
+		pod.Spec.Tolerations = []v1.Toleration{
+			{
+				Key:      archNodeSelectorLabel,
+				Operator: v1.TolerationOpEqual,
+				Value:    cp.arch,
+				Effect:   v1.TaintEffectNoSchedule,
+			},
+		}
+	}
+
+	return pod
 }
 
 // WorkerRunner runs a worker pod on either OpenShift/Kubernetes or
@@ -257,106 +406,168 @@ func (cp *cosaPod) WorkerRunner(ctx ClusterContext, envVars []v1.EnvVar) error {
 	if cluster.inCluster {
 		return clusterRunner(ctx, cp, envVars)
 	}
+	if useLocalKubePlay() {
+		return kubePlayRunner(ctx, cp, envVars)
+	}
 	return podmanRunner(ctx, cp, envVars)
 }
 
 // clusterRunner creates an OpenShift/Kubernetes pod for the work to be done.
 // The output of the pod is streamed and captured on the console.
+//
+// When cp.autoupdatePolicy is autoupdatePolicyRegistry, clusterRunner also
+// watches for a newer worker image digest and, once the worker reaches a
+// stage boundary, deletes the running pod and creates a new one pinned to
+// it, so the build resumes on the updated image.
 func clusterRunner(ctx ClusterContext, cp *cosaPod, envVars []v1.EnvVar) error {
 	cs, ns, err := GetClient(cp.clusterCtx)
 	if err != nil {
 		return err
 	}
 	pod := cp.getPodSpec(envVars)
-
-	ac := cs.CoreV1()
-	resp, err := ac.Pods(ns).Create(pod)
-	if err != nil {
-		return fmt.Errorf("failed to create pod %s: %w", pod.Name, err)
-	}
-	log.Infof("Pod created: %s", pod.Name)
-	cp.pod = pod
-
-	status := resp.Status
-	w, err := ac.Pods(ns).Watch(
-		metav1.ListOptions{
-			Watch:           true,
-			ResourceVersion: resp.ResourceVersion,
-			FieldSelector:   fields.Set{"metadata.name": pod.Name}.AsSelector().String(),
-			LabelSelector:   labels.Everything().String(),
-		},
-	)
-	if err != nil {
-		return err
+	image := pod.Spec.Containers[0].Image
+
+	var updateCh <-chan string
+	if cp.autoupdatePolicy == autoupdatePolicyRegistry {
+		if digest, derr := resolveRegistryDigest(ctx, image); derr != nil {
+			log.WithError(derr).Warn("auto-update: failed to resolve starting digest, disabling for this run")
+		} else {
+			updateCh = cp.watchForAutoUpdate(ctx, image, digest)
+		}
 	}
-	defer w.Stop()
 
-	l := log.WithField("podname", pod.Name)
+	ac := cs.CoreV1()
 
-	// ender is our clean-up that kill our pods
-	ender := func() {
-		l.Infof("terminating")
-		if err := ac.Pods(ns).Delete(pod.Name, &metav1.DeleteOptions{}); err != nil {
-			l.WithError(err).Error("Failed delete on pod, yolo.")
+	// runOnce creates pod, streams its logs until it finishes, and returns
+	// (true, digest, nil) if an auto-update became available and the
+	// worker hit a stage boundary, telling the caller to recreate pod
+	// pinned to digest instead of returning.
+	runOnce := func() (bool, string, error) {
+		resp, err := ac.Pods(ns).Create(pod)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to create pod %s: %w", pod.Name, err)
 		}
-	}
-	defer ender()
+		log.Infof("Pod created: %s", pod.Name)
+		cp.pod = pod
+
+		status := resp.Status
+		w, err := ac.Pods(ns).Watch(
+			metav1.ListOptions{
+				Watch:           true,
+				ResourceVersion: resp.ResourceVersion,
+				FieldSelector:   fields.Set{"metadata.name": pod.Name}.AsSelector().String(),
+				LabelSelector:   labels.Everything().String(),
+			},
+		)
+		if err != nil {
+			return false, "", err
+		}
+		defer w.Stop()
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
+		l := log.WithField("podname", pod.Name)
 
-	logStarted := make(map[string]*bool)
-	// Block waiting for the pod to finish or timeout.
-	for {
-		select {
-		case events, ok := <-w.ResultChan():
-			if !ok {
-				l.Error("failed waitching pod")
-				return fmt.Errorf("orphaned pod")
+		// ender is our clean-up that kill our pods
+		ender := func() {
+			l.Infof("terminating")
+			if err := ac.Pods(ns).Delete(pod.Name, &metav1.DeleteOptions{}); err != nil {
+				l.WithError(err).Error("Failed delete on pod, yolo.")
 			}
-			resp = events.Object.(*v1.Pod)
-			status = resp.Status
-
-			l := log.WithFields(log.Fields{
-				"podname": pod.Name,
-				"status":  resp.Status.Phase,
-			})
-			switch sp := status.Phase; sp {
-			case v1.PodSucceeded:
-				l.Infof("Pod successfully completed")
-				return nil
-			case v1.PodRunning:
-				l.Infof("Pod successfully completed")
-				for _, c := range pod.Spec.InitContainers {
-					logStarted[c.Name] = ptrBool(false)
-					if err := cp.streamPodLogs(logStarted[c.Name], pod, c.Name); err != nil {
-						l.WithField("err", err).Error("failed to open logging for init container")
+		}
+		defer ender()
+
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
+		defer signal.Stop(sigs)
+
+		logStarted := make(map[string]*bool)
+		checkpointRequested := false
+		// Block waiting for the pod to finish or timeout.
+		for {
+			select {
+			case events, ok := <-w.ResultChan():
+				if !ok {
+					l.Error("failed waitching pod")
+					return false, "", fmt.Errorf("orphaned pod")
+				}
+				resp = events.Object.(*v1.Pod)
+				status = resp.Status
+
+				l := log.WithFields(log.Fields{
+					"podname": pod.Name,
+					"status":  resp.Status.Phase,
+				})
+
+				if checkpointRequested {
+					if path, ok := resp.Annotations[checkpointAnnotationKey]; ok && path != "" {
+						l.WithField("checkpoint", path).Info("worker checkpointed itself, tearing down")
+						return false, "", nil
 					}
 				}
-				for _, c := range pod.Spec.Containers {
-					logStarted[c.Name] = ptrBool(false)
-					if err := cp.streamPodLogs(logStarted[c.Name], pod, c.Name); err != nil {
-						l.WithField("err", err).Error("failed to open logging")
+
+				switch sp := status.Phase; sp {
+				case v1.PodSucceeded:
+					l.Infof("Pod successfully completed")
+					return false, "", nil
+				case v1.PodRunning:
+					l.Infof("Pod successfully completed")
+					for _, c := range pod.Spec.InitContainers {
+						logStarted[c.Name] = ptrBool(false)
+						if err := cp.streamPodLogs(logStarted[c.Name], pod, c.Name); err != nil {
+							l.WithField("err", err).Error("failed to open logging for init container")
+						}
+					}
+					for _, c := range pod.Spec.Containers {
+						logStarted[c.Name] = ptrBool(false)
+						if err := cp.streamPodLogs(logStarted[c.Name], pod, c.Name); err != nil {
+							l.WithField("err", err).Error("failed to open logging")
+						}
 					}
+				case v1.PodFailed:
+					l.WithField("message", status.Message).Error("Pod failed")
+					time.Sleep(1 * time.Minute)
+					return false, "", fmt.Errorf("Pod is a failure in its life")
+				default:
+					l.WithField("message", status.Message).Info("waiting...")
+				}
+
+			// Ensure a dreadful and uncerimonious end to our job in case of
+			// a timeout, the buildconfig is terminated, or there's a cancellation.
+			case <-time.After(90 * time.Minute):
+				return false, "", errors.New("Pod did not complete work in time")
+			case sig := <-sigs:
+				if sig == syscall.SIGUSR1 && !checkpointRequested {
+					if err := cp.requestWorkerCheckpoint(); err != nil {
+						l.WithError(err).Error("failed to request checkpoint, falling back to a normal termination")
+						return false, "", errors.New("Termination requested")
+					}
+					l.Info("requested worker checkpoint, waiting for it to patch back the archive path")
+					checkpointRequested = true
+					continue
+				}
+				return false, "", errors.New("Termination requested")
+			case <-ctx.Done():
+				return false, "", nil
+			case digest := <-updateCh:
+				l.WithField("digest", digest).Info("auto-update: waiting for a stage boundary to swap the worker image")
+				select {
+				case <-cp.stageBoundary:
+					return true, digest, nil
+				case <-ctx.Done():
+					return false, "", nil
 				}
-			case v1.PodFailed:
-				l.WithField("message", status.Message).Error("Pod failed")
-				time.Sleep(1 * time.Minute)
-				return fmt.Errorf("Pod is a failure in its life")
-			default:
-				l.WithField("message", status.Message).Info("waiting...")
 			}
+		}
+	}
 
-		// Ensure a dreadful and uncerimonious end to our job in case of
-		// a timeout, the buildconfig is terminated, or there's a cancellation.
-		case <-time.After(90 * time.Minute):
-			return errors.New("Pod did not complete work in time")
-		case <-sigs:
-			ender()
-			return errors.New("Termination requested")
-		case <-ctx.Done():
-			return nil
+	for {
+		pod.Spec.Containers[0].Image = image
+		swap, digest, err := runOnce()
+		if !swap {
+			return err
 		}
+		log.WithField("digest", digest).Info("auto-update: recreating the worker pod with the updated image")
+		image = fmt.Sprintf("%s@%s", strings.SplitN(image, "@", 2)[0], digest)
+		updateCh = cp.watchForAutoUpdate(ctx, image, digest)
 	}
 }
 
@@ -414,7 +625,14 @@ func (cp *cosaPod) streamPodLogs(logging *bool, pod *v1.Pod, container string) e
 			scanner := bufio.NewScanner(podLogs)
 			for scanner.Scan() {
 				since := time.Since(startTime).Truncate(time.Millisecond)
-				fmt.Printf("%s [+%v]: %s\n", container, since, scanner.Text())
+				line := scanner.Text()
+				fmt.Printf("%s [+%v]: %s\n", container, since, line)
+				if strings.Contains(line, stageLogPrefix) {
+					select {
+					case cp.stageBoundary <- struct{}{}:
+					default:
+					}
+				}
 				if _, err := logf.Write(scanner.Bytes()); err != nil {
 					l.WithError(err).Warnf("unable to log to file")
 				}
@@ -445,85 +663,225 @@ func newNoopFileWriterCloser(f *os.File) *outWriteCloser {
 	return &outWriteCloser{f}
 }
 
-// podmanRunner runs the work in a Podman container using workDir as `/srv`
-// `podman kube play` does not work well due to permission mappings; there is
-// no way to do id mappings.
-func podmanRunner(ctx ClusterContext, cp *cosaPod, envVars []v1.EnvVar) error {
-	// Populate pod envvars
-	envVars = append(envVars, v1.EnvVar{Name: localPodEnvVar, Value: "1"})
-	mapEnvVars := map[string]string{
-		localPodEnvVar: "1",
-	}
-	for _, v := range envVars {
-		mapEnvVars[v.Name] = v.Value
+// stageBoundaryWriter tees container stdout to the underlying file while
+// scanning completed lines for the stageLogPrefix marker the worker logs
+// in spec.Stage.Execute, signalling cp.stageBoundary the same way
+// streamPodLogs does for clusterRunner. podmanRunner attaches directly to
+// the container's stdio rather than polling the Kubernetes log API, so it
+// needs its own tee to observe the same boundary.
+type stageBoundaryWriter struct {
+	*outWriteCloser
+	cp  *cosaPod
+	buf []byte
+}
+
+func newStageBoundaryWriter(cp *cosaPod, f *os.File) *stageBoundaryWriter {
+	return &stageBoundaryWriter{outWriteCloser: newNoopFileWriterCloser(f), cp: cp}
+}
+
+func (w *stageBoundaryWriter) Write(p []byte) (int, error) {
+	n, err := w.outWriteCloser.Write(p)
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		line := w.buf[:idx]
+		w.buf = w.buf[idx+1:]
+		if bytes.Contains(line, []byte(stageLogPrefix)) {
+			select {
+			case w.cp.stageBoundary <- struct{}{}:
+			default:
+			}
+		}
 	}
+	return n, err
+}
 
-	// Get our pod spec
-	podSpec := cp.getPodSpec(nil)
-	l := log.WithFields(log.Fields{
-		"method":  "podman",
-		"image":   podSpec.Spec.Containers[0].Image,
-		"podName": podSpec.Name,
-	})
+// checkpoint freezes containerID via Podman's CRIU-backed checkpoint
+// endpoint and exports the resulting archive under srvDir, so it survives
+// the container's removal on the node's local disk (the `srv` EmptyDir, or
+// a PVC when clusterCtx.podmanSrvDir is set). It patches the worker Pod
+// itself with the archive's path so a replacement pod, built via
+// NewCosaPodder, resumes from it instead of starting over; patching the
+// live Pod object (rather than just cp's in-memory apiBuild) is what lets
+// that annotation survive this process exiting. Invoked from podmanRunner's
+// own SIGUSR1 handler, since that is the runner with a local podman
+// container to checkpoint; clusterRunner has no such container and instead
+// asks for this indirectly via requestWorkerCheckpoint, then watches for
+// checkpointAnnotationKey to land on the Pod it created.
+func (cp *cosaPod) checkpoint(connText context.Context, containerID, srvDir string) error {
+	archiveDir := filepath.Join(srvDir, "checkpoints")
+	if err := os.MkdirAll(archiveDir, 0777); err != nil {
+		return fmt.Errorf("failed to create checkpoint dir %s: %w", archiveDir, err)
+	}
+	exportPath := filepath.Join(archiveDir, fmt.Sprintf("%s.tar.gz", containerID))
+
+	cctx, cancel := context.WithTimeout(connText, checkpointTimeout)
+	defer cancel()
+	if _, err := containers.Checkpoint(cctx, containerID, &containers.CheckpointOptions{
+		KeepRunning:    false,
+		TCPEstablished: false,
+		Export:         exportPath,
+	}); err != nil {
+		return fmt.Errorf("failed to checkpoint container %s: %w", containerID, err)
+	}
 
-	cmd := exec.Command("systemctl", "--user", "start", "podman.socket")
-	if err := cmd.Run(); err != nil {
-		l.WithError(err).Fatal("Failed to start podman socket")
+	if err := cp.annotatePodCheckpoint(exportPath); err != nil {
+		return fmt.Errorf("failed to annotate worker pod with checkpoint path: %w", err)
 	}
-	sockDir := os.Getenv("XDG_RUNTIME_DIR")
-	socket := "unix:" + sockDir + "/podman/podman.sock"
 
-	// Connect to Podman socket
-	connText, err := bindings.NewConnection(ctx, socket)
+	log.WithField("checkpoint", exportPath).Info("checkpointed worker container")
+	return nil
+}
+
+// annotatePodCheckpoint patches the worker Pod's checkpointAnnotationKey
+// annotation to exportPath via the API server. NewCosaPodder looks this up
+// by the same deterministic podName() when standing up the replacement
+// worker.
+func (cp *cosaPod) annotatePodCheckpoint(exportPath string) error {
+	cs, ns, err := GetClient(cp.clusterCtx)
 	if err != nil {
 		return err
 	}
+	if cs == nil {
+		return errors.New("no in-cluster client available to annotate the worker pod")
+	}
 
-	rt, err := libpod.NewRuntime(connText)
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				checkpointAnnotationKey: exportPath,
+			},
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get container runtime: %w", err)
+		return fmt.Errorf("failed to build checkpoint annotation patch: %w", err)
 	}
 
-	// Get the StdIO from the cluster context.
-	clusterCtx, err := GetCluster(ctx)
+	if _, err := cs.CoreV1().Pods(ns).Patch(cp.podName(), types.MergePatchType, patch); err != nil {
+		return fmt.Errorf("failed to patch pod %s: %w", cp.podName(), err)
+	}
+	return nil
+}
+
+// requestWorkerCheckpoint patches checkpointRequestAnnotationKey onto the
+// worker Pod, called from clusterRunner's SIGUSR1 handler. clusterRunner
+// only orchestrates the Pod over the API server; it has no local container
+// of its own to hand to containers.Checkpoint the way podmanRunner does, so
+// this is a request rather than the checkpoint itself -- clusterRunner then
+// watches for checkpointAnnotationKey to show up on the same Pod, patched
+// by whatever runs inside it, before tearing down.
+func (cp *cosaPod) requestWorkerCheckpoint() error {
+	cs, ns, err := GetClient(cp.clusterCtx)
 	if err != nil {
 		return err
 	}
-	stdIn, stdOut, stdErr := clusterCtx.GetStdIO()
-	if stdOut == nil {
-		stdOut = os.Stdout
+	if cs == nil {
+		return errors.New("no in-cluster client available to request a checkpoint")
 	}
-	if stdErr == nil {
-		stdErr = os.Stdout
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				checkpointRequestAnnotationKey: "true",
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build checkpoint-request annotation patch: %w", err)
 	}
-	if stdIn == nil {
-		stdIn = os.Stdin
+
+	if _, err := cs.CoreV1().Pods(ns).Patch(cp.podName(), types.MergePatchType, patch); err != nil {
+		return fmt.Errorf("failed to patch pod %s: %w", cp.podName(), err)
+	}
+	return nil
+}
+
+// restoreFromCheckpoint creates a new container named name from the CRIU
+// archive at archivePath, so the worker resumes mid-stage rather than
+// restarting. The checkpoint and restore hosts must agree on the uid map
+// podmanRunner sets up via IDMappings, and on the restore side the /dev/kvm
+// and /dev/fuse handles declared in the container's spec are reopened fresh
+// against the new host rather than replayed from the CRIU dump. The restore
+// is bounded by timeout; callers are expected to fall back to a fresh
+// container on any error.
+func restoreFromCheckpoint(connText context.Context, archivePath, name string, timeout time.Duration) (string, error) {
+	rctx, cancel := context.WithTimeout(connText, timeout)
+	defer cancel()
+
+	report, err := containers.Restore(rctx, "", &containers.RestoreOptions{
+		Name:           name,
+		Import:         archivePath,
+		TCPEstablished: false,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to restore container from %s: %w", archivePath, err)
 	}
+	return report.Id, nil
+}
 
-	streams := &define.AttachStreams{
-		AttachError:  true,
-		AttachOutput: true,
-		AttachInput:  true,
-		InputStream:  bufio.NewReader(stdIn),
-		OutputStream: newNoopFileWriterCloser(stdOut),
-		ErrorStream:  newNoopFileWriterCloser(stdErr),
+// resolveRegistryDigest resolves imageRef's current manifest digest
+// directly against the registry, a la `crane digest`, without pulling the
+// image. This lets both runners check for a newer worker image before
+// committing to a swap.
+func resolveRegistryDigest(ctx context.Context, imageRef string) (string, error) {
+	ref, err := docker.ParseReference("//" + imageRef)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference %s: %w", imageRef, err)
+	}
+	digest, err := docker.GetDigest(ctx, &imgtypes.SystemContext{}, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve registry digest for %s: %w", imageRef, err)
 	}
+	return digest.String(), nil
+}
 
-	s := specgen.NewSpecGenerator(podSpec.Spec.Containers[0].Image)
+// watchForAutoUpdate polls the registry every autoupdatePollInterval for a
+// newer digest of imageRef and, the first time one differs from
+// runningDigest, sends it on the returned channel and exits. Callers only
+// start it when cp.autoupdatePolicy is autoupdatePolicyRegistry.
+func (cp *cosaPod) watchForAutoUpdate(ctx context.Context, imageRef, runningDigest string) <-chan string {
+	updateCh := make(chan string, 1)
+	go func() {
+		ticker := time.NewTicker(autoupdatePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				digest, err := resolveRegistryDigest(ctx, imageRef)
+				if err != nil {
+					log.WithError(err).Warn("auto-update: failed to resolve registry digest")
+					continue
+				}
+				if digest != runningDigest {
+					log.WithField("digest", digest).Info("auto-update: newer worker image digest found")
+					updateCh <- digest
+					return
+				}
+			}
+		}
+	}()
+	return updateCh
+}
+
+// buildContainerSpec assembles the specgen.SpecGenerator for a worker
+// container named name running image, with the uid mapping, devices, and
+// /srv bind mount every worker container needs regardless of which image
+// it runs. podmanRunner calls it once for the initial container and again
+// for every auto-update swap.
+func buildContainerSpec(image, name string, mapEnvVars map[string]string, hostUID int, srvDir string) *specgen.SpecGenerator {
+	s := specgen.NewSpecGenerator(image)
 	s.CapAdd = podmanCaps
-	s.Name = podSpec.Name
-	s.Entrypoint = []string{"/usr/bin/dumb-init", "/usr/bin/gangplank", "builder"}
+	s.Name = name
 	s.ContainerNetworkConfig = specgen.ContainerNetworkConfig{
 		NetNS: specgen.Namespace{
 			NSMode: specgen.Host,
 		},
 	}
-
-	u, err := user.CurrentUser()
-	if err != nil {
-		return fmt.Errorf("unable to lookup the current user: %v", err)
-	}
-
 	s.ContainerSecurityConfig = specgen.ContainerSecurityConfig{
 		Privileged: true,
 		User:       "builder",
@@ -531,12 +889,12 @@ func podmanRunner(ctx ClusterContext, cp *cosaPod, envVars []v1.EnvVar) error {
 			UIDMap: []idtools.IDMap{
 				{
 					ContainerID: 0,
-					HostID:      u.Uid,
+					HostID:      hostUID,
 					Size:        1,
 				},
 				{
 					ContainerID: 1000,
-					HostID:      u.Uid,
+					HostID:      hostUID,
 					Size:        200000,
 				},
 			},
@@ -555,12 +913,96 @@ func podmanRunner(ctx ClusterContext, cp *cosaPod, envVars []v1.EnvVar) error {
 			Type: "char",
 		},
 	}
+	s.WorkDir = "/srv"
+	s.Mounts = []cspec.Mount{
+		{
+			Type:        "bind",
+			Destination: "/srv",
+			Source:      srvDir,
+		},
+	}
+	s.Entrypoint = []string{"/usr/bin/dumb-init"}
+	s.Command = []string{"/usr/bin/gangplank", "builder"}
+
+	if err := s.Validate(); err != nil {
+		log.WithError(err).Error("Validation failed")
+	}
+	return s
+}
+
+// podmanRunner runs the work in a Podman container using workDir as `/srv`
+// `podman kube play` does not work well due to permission mappings; there is
+// no way to do id mappings.
+//
+// This talks to Podman entirely over its remote REST API
+// (pkg/bindings/containers), not the in-process libpod runtime: no
+// CGO-linked libpod code is embedded in gangplank, so the binary can be
+// built and run against whatever Podman v4 the host has installed.
+//
+// When cp.autoupdatePolicy is autoupdatePolicyRegistry, podmanRunner also
+// watches for a newer worker image digest and, once the worker reaches a
+// stage boundary, tears down the running container and starts a new one
+// pinned to it, so the build resumes on the updated image instead of
+// running the whole build on the image it started with.
+func podmanRunner(ctx ClusterContext, cp *cosaPod, envVars []v1.EnvVar) error {
+	// Populate pod envvars
+	envVars = append(envVars, v1.EnvVar{Name: localPodEnvVar, Value: "1"})
+	mapEnvVars := map[string]string{
+		localPodEnvVar: "1",
+	}
+	for _, v := range envVars {
+		mapEnvVars[v.Name] = v.Value
+	}
+
+	// Get our pod spec
+	podSpec := cp.getPodSpec(nil)
+	name := podSpec.Name
+	image := podSpec.Spec.Containers[0].Image
+	l := log.WithFields(log.Fields{
+		"method":  "podman",
+		"image":   image,
+		"podName": name,
+	})
+
+	cmd := exec.Command("systemctl", "--user", "start", "podman.socket")
+	if err := cmd.Run(); err != nil {
+		l.WithError(err).Fatal("Failed to start podman socket")
+	}
+	sockDir := os.Getenv("XDG_RUNTIME_DIR")
+	socket := "unix:" + sockDir + "/podman/podman.sock"
+
+	// Connect to Podman socket
+	connText, err := bindings.NewConnection(ctx, socket)
+	if err != nil {
+		return err
+	}
+
+	// Get the StdIO from the cluster context.
+	clusterCtx, err := GetCluster(ctx)
+	if err != nil {
+		return err
+	}
+	stdIn, stdOut, stdErr := clusterCtx.GetStdIO()
+	if stdOut == nil {
+		stdOut = os.Stdout
+	}
+	if stdErr == nil {
+		stdErr = os.Stdout
+	}
+	if stdIn == nil {
+		stdIn = os.Stdin
+	}
+
+	u, err := user.CurrentUser()
+	if err != nil {
+		return fmt.Errorf("unable to lookup the current user: %v", err)
+	}
 
 	// Ensure that /srv in the COSA container is defined.
 	srvDir := clusterCtx.podmanSrvDir
 	if srvDir == "" {
 		// ioutil.TempDir does not create the directory with the appropriate perms
-		tmpSrvDir := filepath.Join(cosaSrvDir, s.Name)
+		tmpSrvDir := filepath.Join(cosaSrvDir, name)
 		if err := os.MkdirAll(tmpSrvDir, 0777); err != nil {
 			return fmt.Errorf("failed to create emphemeral srv dir for pod: %w", err)
 		}
@@ -574,94 +1016,166 @@ func podmanRunner(ctx ClusterContext, cp *cosaPod, envVars []v1.EnvVar) error {
 			l.WithError(err).Fatalf("failed set selinux context on %s", srvDir)
 		}
 	}
-
 	l.WithField("bind mount", srvDir).Info("using host directory for /srv")
-	s.WorkDir = "/srv"
-	s.Mounts = []cspec.Mount{
-		{
-			Type:        "bind",
-			Destination: "/srv",
-			Source:      srvDir,
-		},
-	}
-	s.Entrypoint = []string{"/usr/bin/dumb-init"}
-	s.Command = []string{"/usr/bin/gangplank", "builder"}
 
-	// Validate and define the container spec
-	if err := s.Validate(); err != nil {
-		l.WithError(err).Error("Validation failed")
-	}
-	r, err := containers.CreateWithSpec(connText, s)
-	if err != nil {
-		return fmt.Errorf("failed to create container: %w", err)
+	var updateCh <-chan string
+	if cp.autoupdatePolicy == autoupdatePolicyRegistry {
+		if digest, derr := resolveRegistryDigest(connText, image); derr != nil {
+			l.WithError(derr).Warn("auto-update: failed to resolve starting digest, disabling for this run")
+		} else {
+			updateCh = cp.watchForAutoUpdate(connText, image, digest)
+		}
 	}
-	// Look up the container.
-	lb, err := rt.LookupContainer(r.ID)
-	if err != nil {
-		return fmt.Errorf("failed to find container: %w", err)
-	}
-
-	// Manually terminate the pod to ensure that we get all the logs first.
-	// Here be hacks: the API is dreadful for streaming logs. Podman,
-	// in this case, is a better UX. There likely is a much better way, but meh,
-	// this works.
-	ender := func() {
-		time.Sleep(1 * time.Second)
-		_ = containers.Remove(connText, r.ID, ptrBool(true), ptrBool(true))
-		if clusterCtx.podmanSrvDir != "" {
-			return
+
+	// Only the very first container started gets to restore from a
+	// checkpoint; auto-update swaps always start fresh.
+	restorePath := cp.checkpointPath
+
+	for {
+		s := buildContainerSpec(image, name, mapEnvVars, u.Uid, srvDir)
+
+		var r *containers.ContainerCreateResponse
+		resumed := false
+		if restorePath != "" {
+			rr, restoreErr := restoreFromCheckpoint(connText, restorePath, s.Name, checkpointRestoreTimeout)
+			if restoreErr != nil {
+				l.WithError(restoreErr).Warn("restore from checkpoint failed, starting fresh instead")
+				r, err = containers.CreateWithSpec(connText, s)
+			} else {
+				l.WithField("checkpoint", restorePath).Info("resumed worker from checkpoint")
+				r = &containers.ContainerCreateResponse{ID: rr}
+				resumed = true
+			}
+			restorePath = ""
+		} else {
+			r, err = containers.CreateWithSpec(connText, s)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create container: %w", err)
 		}
 
-		l.Info("Cleaning up ephemeral /srv")
-		defer os.RemoveAll(srvDir) //nolint
+		// Manually terminate the pod to ensure that we get all the logs first.
+		// Here be hacks: the API is dreadful for streaming logs. Podman,
+		// in this case, is a better UX. There likely is a much better way, but meh,
+		// this works.
+		ender := func() {
+			time.Sleep(1 * time.Second)
+			_ = containers.Remove(connText, r.ID, ptrBool(true), ptrBool(true))
+			if clusterCtx.podmanSrvDir != "" {
+				return
+			}
+
+			l.Info("Cleaning up ephemeral /srv")
+			defer os.RemoveAll(srvDir) //nolint
 
-		s.User = "root"
-		s.Entrypoint = []string{"/bin/rm", "-rvf", "/srv/"}
-		s.Name = fmt.Sprintf("%s-cleaner", s.Name)
-		cR, _ := containers.CreateWithSpec(connText, s)
-		defer containers.Remove(connText, cR.ID, ptrBool(true), ptrBool(true)) //nolint
+			s.User = "root"
+			s.Entrypoint = []string{"/bin/rm", "-rvf", "/srv/"}
+			s.Name = fmt.Sprintf("%s-cleaner", s.Name)
+			cR, _ := containers.CreateWithSpec(connText, s)
+			defer containers.Remove(connText, cR.ID, ptrBool(true), ptrBool(true)) //nolint
 
-		if err := containers.Start(connText, cR.ID, nil); err != nil {
-			l.WithError(err).Info("Failed to start cleanup conatiner")
-			return
+			if err := containers.Start(connText, cR.ID, nil); err != nil {
+				l.WithError(err).Info("Failed to start cleanup conatiner")
+				return
+			}
+			_, err := containers.Wait(connText, cR.ID, nil)
+			if err != nil {
+				l.WithError(err).Error("Failed")
+			}
 		}
-		_, err := containers.Wait(connText, cR.ID, nil)
-		if err != nil {
-			l.WithError(err).Error("Failed")
+
+		// A restored container comes back up as part of the restore call
+		// itself; starting it again would just error.
+		if !resumed {
+			if err := containers.Start(connText, r.ID, nil); err != nil {
+				l.WithError(err).Error("Start of pod failed")
+				ender()
+				return err
+			}
 		}
-	}
-	defer ender()
 
-	if err := containers.Start(connText, r.ID, nil); err != nil {
-		l.WithError(err).Error("Start of pod failed")
-		return err
-	}
+		// Ensure clean-up on signal, i.e. ctrl-c. SIGUSR1 gets one extra step:
+		// checkpoint the worker before tearing it down, so the next pod for
+		// this build can resume instead of restarting from scratch.
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
+		go func() {
+			select {
+			case sig := <-sigs:
+				if sig == syscall.SIGUSR1 {
+					if err := cp.checkpoint(connText, r.ID, srvDir); err != nil {
+						l.WithError(err).Error("checkpoint failed, falling back to a normal termination")
+					}
+				}
+				ender()
+			case <-ctx.Done():
+				ender()
+			}
+		}()
+
+		l.WithFields(log.Fields{
+			"stdIn":  stdIn.Name(),
+			"stdOut": stdOut.Name(),
+			"stdErr": stdErr.Name(),
+		}).Info("binding stdio to continater")
+
+		// Attach and Wait are both remote REST calls now, so there is no
+		// local Container object to block on; run Wait in the background and
+		// race it against ctx so a canceled context still returns promptly.
+		//
+		// attachReady is nil: it exists so a caller can block Start() on the
+		// attach handshake, but Start already happened above, so there is
+		// nothing waiting to receive on it.
+		go func() {
+			if err := containers.Attach(connText, r.ID, bufio.NewReader(stdIn), newStageBoundaryWriter(cp, stdOut), newNoopFileWriterCloser(stdErr), nil, nil); err != nil {
+				l.WithError(err).Error("attach to container failed")
+			}
+		}()
+
+		waitCh := make(chan error, 1)
+		go func() {
+			rc, err := containers.Wait(connText, r.ID, nil)
+			switch {
+			case err != nil:
+				waitCh <- err
+			case rc != 0:
+				waitCh <- errors.New("work pod failed")
+			default:
+				waitCh <- nil
+			}
+		}()
 
-	// Ensure clean-up on signal, i.e. ctrl-c
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
-	go func() {
 		select {
-		case <-sigs:
-			ender()
 		case <-ctx.Done():
+			signal.Stop(sigs)
 			ender()
-		}
-	}()
-
-	l.WithFields(log.Fields{
-		"stdIn":  stdIn.Name(),
-		"stdOut": stdOut.Name(),
-		"stdErr": stdErr.Name(),
-	}).Info("binding stdio to continater")
-	resize := make(chan remotecommand.TerminalSize)
-
-	go func() {
-		_ = lb.Attach(streams, "", resize)
-	}()
+			return ctx.Err()
+		case err := <-waitCh:
+			signal.Stop(sigs)
+			ender()
+			return err
+		case newDigest := <-updateCh:
+			l.WithField("digest", newDigest).Info("auto-update: waiting for a stage boundary to swap the worker image")
+			select {
+			case <-cp.stageBoundary:
+			case err := <-waitCh:
+				signal.Stop(sigs)
+				ender()
+				return err
+			case <-ctx.Done():
+				signal.Stop(sigs)
+				ender()
+				return ctx.Err()
+			}
 
-	if rc, _ := lb.Wait(); rc != 0 {
-		return errors.New("work pod failed")
+			l.Info("auto-update: terminating worker to swap in the updated image")
+			signal.Stop(sigs)
+			// Remove only the container: ender would also wipe an
+			// ephemeral srvDir, which the next container bind-mounts.
+			time.Sleep(1 * time.Second)
+			_ = containers.Remove(connText, r.ID, ptrBool(true), ptrBool(true))
+			image = fmt.Sprintf("%s@%s", strings.SplitN(image, "@", 2)[0], newDigest)
+			updateCh = cp.watchForAutoUpdate(connText, image, newDigest)
+		}
 	}
-	return nil
 }
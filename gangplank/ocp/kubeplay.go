@@ -0,0 +1,304 @@
+package ocp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/containers/podman/v4/pkg/bindings"
+	"github.com/containers/podman/v4/pkg/bindings/containers"
+	"github.com/containers/podman/v4/pkg/bindings/play"
+	"github.com/opencontainers/runc/libcontainer/user"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// localKubePlayEnvVar selects the `podman kube play` runner over the
+// hand-rolled specgen path in podmanRunner. Both runners execute the same
+// getPodSpec output, so this is purely a "how do we submit it" toggle.
+const localKubePlayEnvVar = "COSA_LOCAL_KUBE_PLAY"
+
+// useLocalKubePlay reports whether the local runner should submit pods via
+// `podman kube play` instead of building a specgen.SpecGenerator by hand.
+func useLocalKubePlay() bool {
+	return os.Getenv(localKubePlayEnvVar) != ""
+}
+
+// kubePlayRunner runs the work pod locally via `podman kube play`, feeding
+// it the exact same v1.Pod that clusterRunner submits to OpenShift. Unlike
+// podmanRunner's hand-built specgen container, this shares one pod
+// definition for both execution paths, so /dev/kvm and /dev/fuse behave
+// identically in-cluster and locally. The secret/configMap volumes added by
+// NewCosaPodder do too, but only because writeSecretManifests and
+// writeConfigMapManifests below hand `podman kube play` its own copy of each
+// referenced object -- in-cluster, the kubelet resolves those volumes
+// directly off the Build's pull secrets and the deployment's ConfigMaps.
+func kubePlayRunner(ctx ClusterContext, cp *cosaPod, envVars []v1.EnvVar) error {
+	if cp.checkpointPath != "" {
+		// `podman kube play` has no --import equivalent, so a checkpoint
+		// left by podmanRunner can't be resumed here; start fresh.
+		log.Warn("checkpoint restore is not supported by the kube-play runner, starting fresh")
+	}
+
+	envVars = append(envVars, v1.EnvVar{Name: localPodEnvVar, Value: "1"})
+
+	podSpec := cp.getPodSpec(envVars)
+	l := log.WithFields(log.Fields{
+		"method":  "kube-play",
+		"image":   podSpec.Spec.Containers[0].Image,
+		"podName": podSpec.Name,
+	})
+
+	if err := localizeSecurityContext(podSpec); err != nil {
+		return fmt.Errorf("failed to localize pod security context: %w", err)
+	}
+
+	sockDir := os.Getenv("XDG_RUNTIME_DIR")
+	socket := "unix:" + sockDir + "/podman/podman.sock"
+	connText, err := bindings.NewConnection(ctx, socket)
+	if err != nil {
+		return err
+	}
+
+	yamlPod, err := yaml.Marshal(podSpec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pod spec to YAML: %w", err)
+	}
+	yamlPath := filepath.Join(os.TempDir(), fmt.Sprintf("%s.yaml", podSpec.Name))
+	if err := os.WriteFile(yamlPath, yamlPod, 0644); err != nil {
+		return fmt.Errorf("failed to write pod spec to %s: %w", yamlPath, err)
+	}
+	defer os.Remove(yamlPath) //nolint
+
+	// `podman kube play` has no cluster to resolve ConfigMap volumes
+	// against, so any configMap-mounted volume NewCosaPodder added via
+	// addVolumesFromConfigMapLabels has to be handed over as its own
+	// manifest alongside the pod's.
+	configMapPaths, err := writeConfigMapManifests(ctx, podSpec)
+	if err != nil {
+		return fmt.Errorf("failed to prepare configMap manifests: %w", err)
+	}
+	defer func() {
+		for _, p := range configMapPaths {
+			os.Remove(p) //nolint
+		}
+	}()
+
+	// Same problem as ConfigMaps above, for Secret-backed volumes -- most
+	// importantly the pull secret and signing key addVolumesFromSecretLabels
+	// adds, which would otherwise silently vanish under COSA_LOCAL_KUBE_PLAY=1.
+	secretPaths, err := writeSecretManifests(ctx, podSpec)
+	if err != nil {
+		return fmt.Errorf("failed to prepare secret manifests: %w", err)
+	}
+	defer func() {
+		for _, p := range secretPaths {
+			os.Remove(p) //nolint
+		}
+	}()
+
+	down := func() {
+		if _, err := play.KubeDown(connText, yamlPath, nil); err != nil {
+			l.WithError(err).Warn("failed to tear down kube play pod")
+		}
+	}
+
+	l.Info("submitting pod via podman kube play")
+	if _, err := play.Kube(connText, yamlPath, &play.KubeOptions{
+		Network:    ptrString("host"),
+		ConfigMaps: configMapPaths,
+		Secrets:    secretPaths,
+	}); err != nil {
+		down()
+		return fmt.Errorf("failed to play kube spec: %w", err)
+	}
+	defer down()
+
+	// `podman kube play` names the container `<pod>-<container>`, unlike
+	// podmanRunner which creates and names the container itself.
+	containerName := fmt.Sprintf("%s-%s", podSpec.Name, podSpec.Spec.Containers[0].Name)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		select {
+		case <-sigs:
+			down()
+		case <-ctx.Done():
+			down()
+		}
+	}()
+
+	// Stream the worker's stdio the same way podmanRunner does, so build
+	// logs and the stageBoundary signal aren't lost under
+	// COSA_LOCAL_KUBE_PLAY=1. attachReady is nil: the container is already
+	// running by the time Kube() returns, so there's nothing waiting on it.
+	clusterCtx, err := GetCluster(ctx)
+	if err != nil {
+		return err
+	}
+	stdIn, stdOut, stdErr := clusterCtx.GetStdIO()
+	if stdOut == nil {
+		stdOut = os.Stdout
+	}
+	if stdErr == nil {
+		stdErr = os.Stdout
+	}
+	if stdIn == nil {
+		stdIn = os.Stdin
+	}
+	go func() {
+		if err := containers.Attach(connText, containerName, bufio.NewReader(stdIn), newStageBoundaryWriter(cp, stdOut), newNoopFileWriterCloser(stdErr), nil, nil); err != nil {
+			l.WithError(err).Error("attach to container failed")
+		}
+	}()
+
+	waitCh := make(chan error, 1)
+	go func() {
+		rc, err := containers.Wait(connText, containerName, nil)
+		switch {
+		case err != nil:
+			waitCh <- err
+		case rc != 0:
+			waitCh <- errors.New("work pod failed")
+		default:
+			waitCh <- nil
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-waitCh:
+		return err
+	}
+}
+
+// localizeSecurityContext adapts podSpec in place: it sets hostUsers=false,
+// which tells kube play to run the pod in a user namespace keyed off of
+// /etc/subuid rather than the host's real root, runs every container as the
+// invoking user's UID and init containers as UID 0, all with GID 1000. It
+// sets no explicit UID map, so unlike podmanRunner's
+// storage.IDMappingOptions it does not map container UIDs 0 and 1000 onto
+// the invoking host user; that mapping is left to Podman's user namespace.
+func localizeSecurityContext(podSpec *v1.Pod) error {
+	u, err := user.CurrentUser()
+	if err != nil {
+		return fmt.Errorf("unable to lookup the current user: %w", err)
+	}
+
+	hostUsers := false
+	podSpec.Spec.HostUsers = &hostUsers
+
+	for i := range podSpec.Spec.Containers {
+		podSpec.Spec.Containers[i].SecurityContext = &v1.SecurityContext{
+			RunAsUser:  ptrInt(int64(u.Uid)),
+			RunAsGroup: ptrInt(1000),
+		}
+	}
+	for i := range podSpec.Spec.InitContainers {
+		podSpec.Spec.InitContainers[i].SecurityContext = &v1.SecurityContext{
+			RunAsUser:  ptrInt(0),
+			RunAsGroup: ptrInt(1000),
+		}
+	}
+	return nil
+}
+
+// writeConfigMapManifests fetches the ConfigMap backing each ConfigMap
+// volume in podSpec and writes it out as its own YAML manifest, returning
+// the paths for use as play.KubeOptions.ConfigMaps. `podman kube play`
+// resolves ConfigMap volumes against these manifests instead of a cluster
+// API, unlike clusterRunner's pod which the kubelet resolves directly.
+func writeConfigMapManifests(ctx ClusterContext, podSpec *v1.Pod) ([]string, error) {
+	var cmVolumes []v1.Volume
+	for _, vol := range podSpec.Spec.Volumes {
+		if vol.ConfigMap != nil {
+			cmVolumes = append(cmVolumes, vol)
+		}
+	}
+	if len(cmVolumes) == 0 {
+		return nil, nil
+	}
+
+	cs, ns, err := GetClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("pod %s references configMap volumes but no in-cluster client is available to fetch them", podSpec.Name)
+	}
+
+	var paths []string
+	for _, vol := range cmVolumes {
+		cm, err := cs.CoreV1().ConfigMaps(ns).Get(vol.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch configMap %s: %w", vol.ConfigMap.Name, err)
+		}
+		cm.TypeMeta = metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"}
+
+		data, err := yaml.Marshal(cm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal configMap %s to YAML: %w", vol.ConfigMap.Name, err)
+		}
+		path := filepath.Join(os.TempDir(), fmt.Sprintf("%s-configmap.yaml", vol.ConfigMap.Name))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write configMap manifest %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// writeSecretManifests fetches the Secret backing each Secret volume in
+// podSpec and writes it out as its own YAML manifest, returning the paths
+// for use as play.KubeOptions.Secrets. Mirrors writeConfigMapManifests:
+// `podman kube play` resolves Secret volumes against these manifests
+// instead of a cluster API.
+func writeSecretManifests(ctx ClusterContext, podSpec *v1.Pod) ([]string, error) {
+	var secretVolumes []v1.Volume
+	for _, vol := range podSpec.Spec.Volumes {
+		if vol.Secret != nil {
+			secretVolumes = append(secretVolumes, vol)
+		}
+	}
+	if len(secretVolumes) == 0 {
+		return nil, nil
+	}
+
+	cs, ns, err := GetClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("pod %s references secret volumes but no in-cluster client is available to fetch them", podSpec.Name)
+	}
+
+	var paths []string
+	for _, vol := range secretVolumes {
+		secret, err := cs.CoreV1().Secrets(ns).Get(vol.Secret.SecretName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch secret %s: %w", vol.Secret.SecretName, err)
+		}
+		secret.TypeMeta = metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"}
+
+		data, err := yaml.Marshal(secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal secret %s to YAML: %w", vol.Secret.SecretName, err)
+		}
+		path := filepath.Join(os.TempDir(), fmt.Sprintf("%s-secret.yaml", vol.Secret.SecretName))
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write secret manifest %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func ptrString(s string) *string { return &s }
@@ -0,0 +1,173 @@
+package ocp
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/containers/podman/v4/pkg/bindings"
+	"github.com/containers/podman/v4/pkg/bindings/manifests"
+	buildapiv1 "github.com/openshift/api/build/v1"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+)
+
+// archNodeSelectorLabel is the well-known Kubernetes label carrying a
+// node's CPU architecture, used both as a NodeSelector and a Toleration
+// key so arch-pinned worker pods only land on (and tolerate being on)
+// nodes of their own architecture.
+const archNodeSelectorLabel = "kubernetes.io/arch"
+
+// archImageEnvVar tells a single-arch worker pod which fully-qualified tag
+// to push its build to; MultiArchCosaPodder assembles the manifest list
+// from these tags once every worker finishes.
+const archImageEnvVar = "COSA_ARCH_IMAGE"
+
+// archResult is one architecture worker's outcome.
+type archResult struct {
+	arch string
+	err  error
+}
+
+// MultiArchCosaPodder fans a single build out across one worker pod per
+// requested architecture and assembles the resulting per-arch images into
+// a single OCI manifest list, mirroring `podman manifest create/add/push`.
+type MultiArchCosaPodder struct {
+	ctx      ClusterContext
+	apiBuild *buildapiv1.Build
+	index    int
+	arches   []string
+	manifest string
+}
+
+// a MultiArchCosaPodder is a CosaPodder
+var _ CosaPodder = &MultiArchCosaPodder{}
+
+// NewMultiArchCosaPodder creates a MultiArchCosaPodder that schedules one
+// worker pod per entry in arches and assembles their output into manifest
+// (a fully-qualified image reference, e.g. "quay.io/foo/bar:stream").
+func NewMultiArchCosaPodder(
+	ctx ClusterContext,
+	apiBuild *buildapiv1.Build,
+	index int,
+	arches []string,
+	manifest string) *MultiArchCosaPodder {
+
+	return &MultiArchCosaPodder{
+		ctx:      ctx,
+		apiBuild: apiBuild,
+		index:    index,
+		arches:   arches,
+		manifest: manifest,
+	}
+}
+
+// WorkerRunner runs one worker pod per architecture concurrently, fans
+// their results back in, and pushes a manifest list for whichever
+// architectures succeeded. If any architecture fails, the manifest list is
+// still pushed under a "-partial" tag rather than dropped entirely, and
+// WorkerRunner returns an error describing the failed architectures.
+func (m *MultiArchCosaPodder) WorkerRunner(ctx ClusterContext, envVars []v1.EnvVar) error {
+	if len(m.arches) == 0 {
+		return fmt.Errorf("no architectures requested")
+	}
+
+	results := make(chan archResult, len(m.arches))
+	var wg sync.WaitGroup
+	for _, arch := range m.arches {
+		wg.Add(1)
+		go func(arch string) {
+			defer wg.Done()
+			results <- m.runArch(ctx, arch, envVars)
+		}(arch)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var succeeded, failed []archResult
+	for r := range results {
+		if r.err != nil {
+			log.WithError(r.err).WithField("arch", r.arch).Error("architecture worker failed")
+			failed = append(failed, r)
+			continue
+		}
+		succeeded = append(succeeded, r)
+	}
+	if len(succeeded) == 0 {
+		return fmt.Errorf("all %d architecture workers failed", len(m.arches))
+	}
+
+	dest := m.manifest
+	if len(failed) > 0 {
+		dest = fmt.Sprintf("%s-partial", m.manifest)
+	}
+	if err := m.assembleManifest(ctx, succeeded, dest); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d architecture workers failed; pushed partial manifest list to %s",
+			len(failed), len(m.arches), dest)
+	}
+	log.Infof("pushed manifest list %s with %d architectures", dest, len(succeeded))
+	return nil
+}
+
+// archImage returns the fully-qualified tag a single arch's worker should
+// push its image to.
+func (m *MultiArchCosaPodder) archImage(arch string) string {
+	return fmt.Sprintf("%s-%s", m.manifest, arch)
+}
+
+// runArch builds and runs a single-arch worker pod for arch, pinning it to
+// nodes of that architecture and telling it where to push its image.
+func (m *MultiArchCosaPodder) runArch(ctx ClusterContext, arch string, envVars []v1.EnvVar) archResult {
+	cp, err := NewCosaPodder(ctx, m.apiBuild, m.index)
+	if err != nil {
+		return archResult{arch: arch, err: fmt.Errorf("failed to create pod for arch %s: %w", arch, err)}
+	}
+	acp, ok := cp.(*cosaPod)
+	if !ok {
+		return archResult{arch: arch, err: fmt.Errorf("unexpected CosaPodder implementation for arch %s", arch)}
+	}
+	acp.arch = arch
+
+	archEnv := append(append([]v1.EnvVar{}, envVars...), v1.EnvVar{
+		Name:  archImageEnvVar,
+		Value: m.archImage(arch),
+	})
+
+	if err := acp.WorkerRunner(ctx, archEnv); err != nil {
+		return archResult{arch: arch, err: err}
+	}
+	return archResult{arch: arch}
+}
+
+// assembleManifest creates dest as a manifest list, adds every succeeded
+// architecture's pushed image to it, and pushes the list to the registry.
+func (m *MultiArchCosaPodder) assembleManifest(ctx ClusterContext, results []archResult, dest string) error {
+	sockDir := os.Getenv("XDG_RUNTIME_DIR")
+	socket := "unix:" + sockDir + "/podman/podman.sock"
+	connText, err := bindings.NewConnection(ctx, socket)
+	if err != nil {
+		return err
+	}
+
+	manifestID, err := manifests.Create(connText, m.manifest, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest list %s: %w", m.manifest, err)
+	}
+
+	for _, r := range results {
+		image := m.archImage(r.arch)
+		if _, err := manifests.Add(connText, manifestID, &manifests.AddOptions{Images: []string{image}}); err != nil {
+			return fmt.Errorf("failed to add %s image %s to manifest list: %w", r.arch, image, err)
+		}
+	}
+
+	if err := manifests.Push(connText, manifestID, dest, nil); err != nil {
+		return fmt.Errorf("failed to push manifest list to %s: %w", dest, err)
+	}
+	return nil
+}
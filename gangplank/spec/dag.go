@@ -0,0 +1,319 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StageStatus is the lifecycle state of a stage node within a DAG run.
+type StageStatus string
+
+const (
+	// StagePending has not been scheduled yet.
+	StagePending StageStatus = "pending"
+	// StageRunning is currently executing.
+	StageRunning StageStatus = "running"
+	// StageSucceeded completed without error.
+	StageSucceeded StageStatus = "succeeded"
+	// StageFailed completed with an error.
+	StageFailed StageStatus = "failed"
+	// StageSkipped was never run, either because a dependency failed
+	// or a RequireArtifacts entry could not be satisfied.
+	StageSkipped StageStatus = "skipped"
+)
+
+// stageNode is a single Stage placed in the dependency graph.
+type stageNode struct {
+	stage  *Stage
+	deps   []*stageNode
+	status StageStatus
+	err    error
+}
+
+// dag is the resolved set of stage nodes for a JobSpec run.
+type dag struct {
+	nodes []*stageNode
+}
+
+// BuildDAG resolves the dependency graph for stages based purely on
+// RequireArtifacts and BuildArtifacts: a stage A depends on stage B when
+// A requires an artifact that B builds. Artifacts that are already
+// present (e.g. from a prior run's meta.json, passed in as
+// presentArtifacts) and that no stage builds satisfy a requirement
+// without introducing an edge; if some stage does build one, the edge
+// to that stage is still added.
+//
+// Stages whose requirements can be satisfied by neither an ancestor nor
+// presentArtifacts are not dropped here; they are marked StageSkipped so
+// that Run can log why and skip them without disturbing the rest of the
+// graph. BuildDAG returns an error only on a cycle, since a cycle means
+// the graph itself is invalid and cannot be scheduled at all.
+func BuildDAG(stages []*Stage, presentArtifacts []string) (*dag, error) {
+	have := make(map[string]bool, len(presentArtifacts))
+	for _, a := range presentArtifacts {
+		have[a] = true
+	}
+
+	nodes := make([]*stageNode, len(stages))
+	for i, s := range stages {
+		nodes[i] = &stageNode{stage: s, status: StagePending}
+	}
+
+	producedBy := func(artifact string) []*stageNode {
+		var producers []*stageNode
+		for _, n := range nodes {
+			for _, ba := range n.stage.BuildArtifacts {
+				if ba == artifact {
+					producers = append(producers, n)
+				}
+			}
+		}
+		return producers
+	}
+
+	for i, n := range nodes {
+		for _, ra := range n.stage.RequireArtifacts {
+			producers := producedBy(ra)
+			if len(producers) == 0 {
+				if have[ra] {
+					continue
+				}
+				log.WithFields(log.Fields{
+					"stage":    n.stage.ID,
+					"artifact": ra,
+				}).Warn("required artifact is not built by any stage nor present in build meta; stage will be skipped")
+				n.status = StageSkipped
+				n.err = fmt.Errorf("required artifact %q is neither built nor present", ra)
+				continue
+			}
+			for _, p := range producers {
+				if p == n {
+					continue
+				}
+				n.deps = append(n.deps, p)
+			}
+		}
+		nodes[i] = n
+	}
+
+	d := &dag{nodes: nodes}
+	if cyc := d.findCycle(); cyc != nil {
+		return nil, fmt.Errorf("stage dependency cycle detected: %s", cyc)
+	}
+	return d, nil
+}
+
+// findCycle does a DFS over the dependency edges looking for a back
+// edge, returning a human-readable description of the cycle if found.
+func (d *dag) findCycle() error {
+	const (
+		white = 0 // unvisited
+		gray  = 1 // on the current DFS path
+		black = 2 // fully explored
+	)
+	color := make(map[*stageNode]int, len(d.nodes))
+	path := make([]string, 0, len(d.nodes))
+
+	var visit func(n *stageNode) error
+	visit = func(n *stageNode) error {
+		color[n] = gray
+		path = append(path, n.stage.ID)
+		for _, dep := range n.deps {
+			switch color[dep] {
+			case gray:
+				return fmt.Errorf("%s -> %s", joinPath(path), dep.stage.ID)
+			case white:
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		color[n] = black
+		return nil
+	}
+
+	for _, n := range d.nodes {
+		if color[n] == white {
+			if err := visit(n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinPath(path []string) string {
+	out := path[0]
+	for _, p := range path[1:] {
+		out += " -> " + p
+	}
+	return out
+}
+
+// runnable returns the nodes that are still pending and whose
+// dependencies have all reached a terminal state. A dependency that
+// failed or was skipped causes the dependent to be skipped rather than
+// run.
+func (d *dag) runnable(reporter StageReporter) (ready []*stageNode, done bool) {
+	done = true
+	for _, n := range d.nodes {
+		if n.status != StagePending {
+			continue
+		}
+		done = false
+
+		blocked := false
+		for _, dep := range n.deps {
+			switch dep.status {
+			case StageSucceeded:
+				continue
+			case StageFailed, StageSkipped:
+				blocked = true
+			default:
+				blocked = true
+				done = false
+			}
+		}
+		if blocked {
+			anyUnresolved := false
+			for _, dep := range n.deps {
+				if dep.status == StagePending || dep.status == StageRunning {
+					anyUnresolved = true
+				}
+			}
+			if anyUnresolved {
+				continue
+			}
+			log.WithField("stage", n.stage.ID).Warn("skipping stage: a required dependency did not succeed")
+			n.status = StageSkipped
+			n.err = fmt.Errorf("a required dependency did not succeed")
+			reporter.Finished(n.stage.ID, StageSkipped, n.err)
+			continue
+		}
+		ready = append(ready, n)
+	}
+	return ready, done
+}
+
+// Run executes every stage in the DAG, dispatching stages whose
+// dependencies have completed to run concurrently with one another,
+// until the graph is fully resolved. The first stage failure is
+// returned once all already-running stages have finished, and every
+// stage that could not run as a result is left in StageSkipped.
+//
+// deadline, when non-zero, is JobSpec.TimeoutDeadline measured from the
+// start of the run: any stage that has not yet started once the
+// deadline has passed is skipped rather than dispatched, so a slow
+// upstream stage cannot cause the whole job to run indefinitely long
+// past its budget.
+func (d *dag) Run(ctx context.Context, rd *RenderData, envVars []string, deadline time.Time, reporter StageReporter) error {
+	if reporter == nil {
+		reporter = NoopReporter
+	}
+
+	// BuildDAG may have already marked some nodes StageSkipped because
+	// a RequireArtifacts entry could not be satisfied; report those
+	// once up front so subscribers see every stage's terminal state.
+	for _, n := range d.nodes {
+		if n.status == StageSkipped {
+			reporter.Finished(n.stage.ID, StageSkipped, n.err)
+		}
+	}
+
+	var firstErr error
+
+	for {
+		ready, done := d.runnable(reporter)
+		if done {
+			break
+		}
+		if len(ready) == 0 {
+			// Nothing runnable, but the graph isn't done: everything
+			// left pending is blocked on a failed/skipped dependency
+			// and was just marked skipped by runnable(). Loop once
+			// more to pick up the now-terminal states.
+			continue
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			for _, n := range ready {
+				log.WithField("stage", n.stage.ID).Warn("deadline exceeded, not starting")
+				n.status = StageSkipped
+				n.err = fmt.Errorf("deadline exceeded, not starting")
+				reporter.Finished(n.stage.ID, StageSkipped, n.err)
+			}
+			continue
+		}
+
+		wg := &sync.WaitGroup{}
+		for _, n := range ready {
+			n.status = StageRunning
+			wg.Add(1)
+			go func(n *stageNode) {
+				defer wg.Done()
+				log.WithField("stage", n.stage.ID).Info("starting stage")
+				if err := n.stage.Execute(ctx, rd, envVars, reporter); err != nil {
+					n.status = StageFailed
+					n.err = err
+					log.WithError(err).WithField("stage", n.stage.ID).Error("stage failed")
+					return
+				}
+				n.status = StageSucceeded
+			}(n)
+		}
+		wg.Wait()
+
+		for _, n := range ready {
+			if n.status == StageFailed && firstErr == nil {
+				firstErr = fmt.Errorf("stage %s: %w", n.stage.ID, n.err)
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// Execute runs all of the JobSpec's stages as a DAG, honoring
+// RequireArtifacts/BuildArtifacts dependency edges instead of the old
+// integer ExecutionOrder buckets. presentArtifacts are artifact names
+// already known to exist (e.g. from a previous build's meta.json) and
+// therefore satisfy a RequireArtifacts entry without needing a producer
+// stage in this run. reporter may be nil for the historical in-process
+// behavior; the gangplank daemon passes its own DB-backed reporter to
+// persist every stage transition instead.
+func (j *JobSpec) Execute(ctx context.Context, rd *RenderData, envVars []string, presentArtifacts []string, reporter StageReporter) error {
+	if err := j.ExpandMatrix(); err != nil {
+		return fmt.Errorf("failed to expand matrix stages: %w", err)
+	}
+
+	stages := make([]*Stage, len(j.Stages))
+	for i := range j.Stages {
+		stages[i] = &j.Stages[i]
+	}
+
+	d, err := BuildDAG(stages, presentArtifacts)
+	if err != nil {
+		return err
+	}
+
+	var deadline time.Time
+	if j.TimeoutDeadline > 0 {
+		deadline = time.Now().Add(j.TimeoutDeadline)
+	}
+	if err := d.Run(ctx, rd, envVars, deadline, reporter); err != nil {
+		return err
+	}
+	if j.Job.StrictMode {
+		for _, n := range d.nodes {
+			if n.status == StageSkipped {
+				return fmt.Errorf("strict mode: stage %s was skipped: %w", n.stage.ID, n.err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package spec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildDAGLinearChain(t *testing.T) {
+	base := &Stage{ID: "base", BuildArtifacts: []string{"ostree"}}
+	qemu := &Stage{ID: "qemu", RequireArtifacts: []string{"ostree"}, BuildArtifacts: []string{"qemu"}}
+	liveISO := &Stage{ID: "live-iso", RequireArtifacts: []string{"qemu"}, BuildArtifacts: []string{"live-iso"}}
+
+	d, err := BuildDAG([]*Stage{base, qemu, liveISO}, nil)
+	if err != nil {
+		t.Fatalf("BuildDAG returned an error for an acyclic graph: %v", err)
+	}
+
+	byID := make(map[string]*stageNode, len(d.nodes))
+	for _, n := range d.nodes {
+		byID[n.stage.ID] = n
+	}
+	if len(byID["qemu"].deps) != 1 || byID["qemu"].deps[0].stage.ID != "base" {
+		t.Fatalf("qemu should depend on base, got deps %v", byID["qemu"].deps)
+	}
+	if len(byID["live-iso"].deps) != 1 || byID["live-iso"].deps[0].stage.ID != "qemu" {
+		t.Fatalf("live-iso should depend on qemu, got deps %v", byID["live-iso"].deps)
+	}
+}
+
+func TestBuildDAGPresentArtifactsSatisfyRequirement(t *testing.T) {
+	qemu := &Stage{ID: "qemu", RequireArtifacts: []string{"ostree"}, BuildArtifacts: []string{"qemu"}}
+
+	d, err := BuildDAG([]*Stage{qemu}, []string{"ostree"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.nodes[0].status == StageSkipped {
+		t.Fatal("stage should not be skipped when its requirement is already present")
+	}
+	if len(d.nodes[0].deps) != 0 {
+		t.Fatalf("a present artifact should not introduce a dependency edge, got %v", d.nodes[0].deps)
+	}
+}
+
+func TestBuildDAGMissingRequirementSkipsStage(t *testing.T) {
+	orphan := &Stage{ID: "orphan", RequireArtifacts: []string{"does-not-exist"}}
+
+	d, err := BuildDAG([]*Stage{orphan}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.nodes[0].status != StageSkipped {
+		t.Fatalf("expected stage with an unsatisfiable requirement to be skipped, got %s", d.nodes[0].status)
+	}
+}
+
+func TestBuildDAGSelfLoopIsNotACycle(t *testing.T) {
+	// A stage that both requires and builds the same artifact (e.g. an
+	// incremental stage re-consuming its own output) must not be treated
+	// as depending on itself.
+	s := &Stage{ID: "self", RequireArtifacts: []string{"thing"}, BuildArtifacts: []string{"thing"}}
+
+	d, err := BuildDAG([]*Stage{s}, nil)
+	if err != nil {
+		t.Fatalf("a self-referencing stage should not be reported as a cycle: %v", err)
+	}
+	if len(d.nodes[0].deps) != 0 {
+		t.Fatalf("a stage should not depend on itself, got %v", d.nodes[0].deps)
+	}
+}
+
+func TestBuildDAGDetectsTwoCycle(t *testing.T) {
+	a := &Stage{ID: "a", RequireArtifacts: []string{"y"}, BuildArtifacts: []string{"x"}}
+	b := &Stage{ID: "b", RequireArtifacts: []string{"x"}, BuildArtifacts: []string{"y"}}
+
+	_, err := BuildDAG([]*Stage{a, b}, nil)
+	if err == nil {
+		t.Fatal("expected BuildDAG to reject a two-stage cycle")
+	}
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected a cycle error, got: %v", err)
+	}
+}
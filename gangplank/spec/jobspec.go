@@ -0,0 +1,48 @@
+package spec
+
+import (
+	"context"
+	"time"
+)
+
+// Job carries job-wide metadata about the invoking BuildConfig/job that
+// isn't specific to any one stage.
+type Job struct {
+	// StrictMode fails the whole job on any stage's skip/failure, rather
+	// than the historical best-effort behavior. Set by GenerateStages for
+	// synthesized jobs, since a skipped synthetic stage usually means a
+	// requested artifact silently didn't get built.
+	StrictMode bool `yaml:"strict,omitempty" json:"strict,omitempty"`
+}
+
+// JobSpec is gangplank's work order: the stages to run, plus job-wide
+// settings that apply across all of them.
+type JobSpec struct {
+	Job    Job     `yaml:"job,omitempty" json:"job,omitempty"`
+	Stages []Stage `yaml:"stages,omitempty" json:"stages,omitempty"`
+
+	// DelayedMetaMerge tells cosaBuildCmd to pass --delay-meta-merge to
+	// `cosa build`, so a distributed, multi-pod build's meta.json is
+	// merged once at the end (via the "finalize" shorthand) instead of
+	// after every stage.
+	DelayedMetaMerge bool `yaml:"delay_meta_merge,omitempty" json:"delay_meta_merge,omitempty"`
+
+	// TimeoutDeadline bounds the wall-clock time of an entire DAG run,
+	// measured from the start of Execute. Zero means unbounded, which is
+	// the historical behavior. See dag.go's Run: any stage that hasn't
+	// started once the deadline passes is skipped rather than dispatched.
+	TimeoutDeadline time.Duration `yaml:"timeout_deadline,omitempty" json:"timeout_deadline,omitempty"`
+}
+
+// RenderData carries the per-run context threaded through Stage.Execute:
+// the JobSpec the running stage belongs to, and the function that
+// actually executes a rendered script.
+type RenderData struct {
+	JobSpec *JobSpec
+
+	// RendererExecuter runs the script at scriptPath with envVars set,
+	// returning its error if any. Implementations must honor ctx
+	// cancellation by terminating the script; see execScript/killOrphan
+	// in stages.go for the backstop when they don't.
+	RendererExecuter func(ctx context.Context, envVars []string, scriptPath string) error
+}
@@ -0,0 +1,138 @@
+package spec
+
+import (
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// matrixPlaceholder matches `{{ .Matrix.<key> }}`, with or without
+// surrounding whitespace inside the braces.
+var matrixPlaceholder = regexp.MustCompile(`{{\s*\.Matrix\.(\w+)\s*}}`)
+
+// itemPlaceholder matches the WithItems shorthand `{{ .Item }}`.
+var itemPlaceholder = regexp.MustCompile(`{{\s*\.Item\s*}}`)
+
+// ExpandMatrix replaces every Matrix'd or WithItems'd stage in the
+// JobSpec with one derived Stage per combination of its axes. Stages
+// without Matrix or WithItems set pass through unchanged. Downstream
+// stages need no special handling to "wait for the group": since every
+// derived stage keeps its BuildArtifacts, BuildDAG already gives a
+// stage that RequireArtifacts one of those names an edge to every
+// producer, matrix or not.
+func (j *JobSpec) ExpandMatrix() error {
+	expanded := make([]Stage, 0, len(j.Stages))
+	for _, s := range j.Stages {
+		if len(s.Matrix) == 0 && len(s.WithItems) == 0 {
+			expanded = append(expanded, s)
+			continue
+		}
+
+		combos, err := s.matrixCombinations()
+		if err != nil {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"stage":        s.ID,
+			"combinations": len(combos),
+		}).Info("expanding matrix stage")
+
+		for _, vars := range combos {
+			expanded = append(expanded, s.substituteMatrix(vars))
+		}
+	}
+	j.Stages = expanded
+	return nil
+}
+
+// matrixCombinations returns the Cartesian product of the stage's
+// Matrix axes, folding WithItems in as an "Item" axis so both forms
+// share the same expansion path.
+func (s *Stage) matrixCombinations() ([]map[string]string, error) {
+	axes := make(map[string][]string, len(s.Matrix)+1)
+	for k, v := range s.Matrix {
+		axes[k] = v
+	}
+
+	combos := []map[string]string{{}}
+	for key, values := range axes {
+		var next []map[string]string
+		for _, base := range combos {
+			for _, v := range values {
+				combo := make(map[string]string, len(base)+1)
+				for bk, bv := range base {
+					combo[bk] = bv
+				}
+				combo[key] = v
+				next = append(next, combo)
+			}
+		}
+		combos = next
+	}
+
+	if len(s.WithItems) == 0 {
+		return combos, nil
+	}
+
+	// WithItems is an independent "Item" axis, combined with any
+	// Matrix axes already expanded above.
+	var withItemCombos []map[string]string
+	for _, base := range combos {
+		for _, item := range s.WithItems {
+			combo := make(map[string]string, len(base)+1)
+			for bk, bv := range base {
+				combo[bk] = bv
+			}
+			combo["__item__"] = item
+			withItemCombos = append(withItemCombos, combo)
+		}
+	}
+	return withItemCombos, nil
+}
+
+// substituteMatrix returns a derived copy of s with `{{ .Matrix.<key> }}`
+// and `{{ .Item }}` placeholders replaced throughout Commands,
+// PrepCommands, PostCommands, BuildArtifacts, and ID, per the values in
+// vars. Matrix siblings already run in parallel with each other via
+// BuildDAG, so ConcurrentExecution is left as the template stage set it;
+// it governs that stage's own Commands/BuildArtifacts ordering, not its
+// siblings'.
+func (s *Stage) substituteMatrix(vars map[string]string) Stage {
+	ns, err := s.DeepCopy()
+	if err != nil {
+		// DeepCopy only fails on a JSON round-trip of a well-formed
+		// Stage, which cannot happen here; fall back to a shallow
+		// value copy rather than losing the stage entirely.
+		ns = *s
+	}
+	ns.Matrix = nil
+	ns.WithItems = nil
+
+	item := vars["__item__"]
+	replace := func(in string) string {
+		out := matrixPlaceholder.ReplaceAllStringFunc(in, func(m string) string {
+			key := matrixPlaceholder.FindStringSubmatch(m)[1]
+			if v, ok := vars[key]; ok {
+				return v
+			}
+			return m
+		})
+		return itemPlaceholder.ReplaceAllString(out, item)
+	}
+	replaceAll := func(in []string) []string {
+		out := make([]string, len(in))
+		for i, v := range in {
+			out[i] = replace(v)
+		}
+		return out
+	}
+
+	ns.ID = replace(s.ID)
+	ns.Commands = replaceAll(s.Commands)
+	ns.PrepCommands = replaceAll(s.PrepCommands)
+	ns.PostCommands = replaceAll(s.PostCommands)
+	ns.BuildArtifacts = replaceAll(s.BuildArtifacts)
+
+	return ns
+}
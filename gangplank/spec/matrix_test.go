@@ -0,0 +1,105 @@
+package spec
+
+import "testing"
+
+func TestMatrixCombinationsCartesianProduct(t *testing.T) {
+	s := &Stage{
+		Matrix: map[string][]string{
+			"arch": {"x86_64", "aarch64"},
+			"disk": {"qcow2"},
+		},
+	}
+	combos, err := s.matrixCombinations()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(combos) != 2 {
+		t.Fatalf("expected 2 combinations (2 arches x 1 disk), got %d: %v", len(combos), combos)
+	}
+	seenArch := map[string]bool{}
+	for _, c := range combos {
+		if c["disk"] != "qcow2" {
+			t.Errorf("expected disk=qcow2 in every combo, got %v", c)
+		}
+		seenArch[c["arch"]] = true
+	}
+	if !seenArch["x86_64"] || !seenArch["aarch64"] {
+		t.Errorf("expected both arches to appear across combos, got %v", combos)
+	}
+}
+
+func TestMatrixCombinationsWithItemsOnly(t *testing.T) {
+	// An empty Matrix combined with WithItems should produce exactly one
+	// combo per item, not zero (the empty Matrix axis must not collapse
+	// the Cartesian product to nothing).
+	s := &Stage{WithItems: []string{"a", "b", "c"}}
+	combos, err := s.matrixCombinations()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(combos) != 3 {
+		t.Fatalf("expected 3 combinations, one per item, got %d: %v", len(combos), combos)
+	}
+	items := map[string]bool{}
+	for _, c := range combos {
+		items[c["__item__"]] = true
+	}
+	for _, want := range []string{"a", "b", "c"} {
+		if !items[want] {
+			t.Errorf("expected item %q among combos, got %v", want, combos)
+		}
+	}
+}
+
+func TestMatrixCombinationsMatrixAndWithItems(t *testing.T) {
+	s := &Stage{
+		Matrix:    map[string][]string{"arch": {"x86_64", "aarch64"}},
+		WithItems: []string{"a", "b"},
+	}
+	combos, err := s.matrixCombinations()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(combos) != 4 {
+		t.Fatalf("expected 4 combinations (2 arches x 2 items), got %d: %v", len(combos), combos)
+	}
+}
+
+func TestSubstituteMatrixReplacesPlaceholders(t *testing.T) {
+	s := &Stage{
+		ID:             "build-{{ .Matrix.arch }}",
+		Commands:       []string{"cosa buildextend-{{ .Matrix.arch }}"},
+		BuildArtifacts: []string{"{{ .Item }}"},
+	}
+	ns := s.substituteMatrix(map[string]string{"arch": "x86_64", "__item__": "qemu"})
+
+	if ns.ID != "build-x86_64" {
+		t.Errorf("ID = %q, want %q", ns.ID, "build-x86_64")
+	}
+	if ns.Commands[0] != "cosa buildextend-x86_64" {
+		t.Errorf("Commands[0] = %q, want %q", ns.Commands[0], "cosa buildextend-x86_64")
+	}
+	if ns.BuildArtifacts[0] != "qemu" {
+		t.Errorf("BuildArtifacts[0] = %q, want %q", ns.BuildArtifacts[0], "qemu")
+	}
+	if ns.Matrix != nil || ns.WithItems != nil {
+		t.Errorf("derived stage should not carry Matrix/WithItems forward, got %v / %v", ns.Matrix, ns.WithItems)
+	}
+}
+
+func TestSubstituteMatrixPreservesConcurrentExecution(t *testing.T) {
+	// A stage author who explicitly serialized their own
+	// Commands/BuildArtifacts via ConcurrentExecution: false must keep
+	// that ordering after matrix expansion; it has nothing to do with
+	// matrix siblings running in parallel with each other (BuildDAG
+	// already gives that for free).
+	serial := &Stage{ID: "s", Matrix: map[string][]string{"k": {"v"}}, ConcurrentExecution: false}
+	if ns := serial.substituteMatrix(map[string]string{"k": "v"}); ns.ConcurrentExecution {
+		t.Error("substituteMatrix must not force ConcurrentExecution=true on a serial stage")
+	}
+
+	concurrent := &Stage{ID: "s", Matrix: map[string][]string{"k": {"v"}}, ConcurrentExecution: true}
+	if ns := concurrent.substituteMatrix(map[string]string{"k": "v"}); !ns.ConcurrentExecution {
+		t.Error("substituteMatrix must preserve an explicit ConcurrentExecution=true")
+	}
+}
@@ -0,0 +1,32 @@
+package spec
+
+// StageReporter receives structured lifecycle events for a stage as it
+// runs. Stage.Execute calls it directly so that any subscriber -- today
+// just NoopReporter, and the gangplank daemon's DB-backed reporter --
+// can observe progress without Stage.Execute knowing anything about
+// persistence or RPC.
+type StageReporter interface {
+	// Started is called once, right before the stage's prep phase runs.
+	Started(stageID string)
+
+	// Finished is called exactly once, with the stage's terminal
+	// status and the error that produced it (nil on StageSucceeded).
+	Finished(stageID string, status StageStatus, err error)
+
+	// Output is called for each line of a command attempt's captured
+	// stdout/stderr, so a subscriber can stream logs live.
+	Output(stageID string, attempt int, line string)
+}
+
+// noopReporter is the default StageReporter: it drops every event. This
+// preserves the pre-daemon in-process behavior of Stage.Execute, where
+// nobody is listening for stage transitions.
+type noopReporter struct{}
+
+func (noopReporter) Started(string)                     {}
+func (noopReporter) Finished(string, StageStatus, error) {}
+func (noopReporter) Output(string, int, string)          {}
+
+// NoopReporter is the shared no-op StageReporter instance, used
+// whenever a caller passes a nil reporter to Stage.Execute.
+var NoopReporter StageReporter = noopReporter{}
@@ -0,0 +1,47 @@
+package spec
+
+import "testing"
+
+func TestAttemptsSatisfyThreshold(t *testing.T) {
+	cases := []struct {
+		name       string
+		successes  int
+		runs       int
+		threshold  float64
+		wantSatisf bool
+	}{
+		{"all succeed, default threshold", 3, 3, 1.0, true},
+		{"one failure, default threshold", 2, 3, 1.0, false},
+		{"exactly at threshold boundary", 2, 4, 0.5, true},
+		{"just below threshold boundary", 1, 4, 0.5, false},
+		{"zero successes", 0, 3, 0.5, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := attemptsSatisfyThreshold(c.successes, c.runs, c.threshold)
+			if got != c.wantSatisf {
+				t.Errorf("attemptsSatisfyThreshold(%d, %d, %v) = %v, want %v",
+					c.successes, c.runs, c.threshold, got, c.wantSatisf)
+			}
+		})
+	}
+}
+
+func TestRetryOnMatches(t *testing.T) {
+	haystacks := []string{"exit status 1", "error: flaky network timeout"}
+
+	if !retryOnMatches([]string{"timeout"}, haystacks) {
+		t.Error("expected a pattern matching captured output to match")
+	}
+	if retryOnMatches([]string{"permission denied"}, haystacks) {
+		t.Error("expected a non-matching pattern not to match")
+	}
+	if retryOnMatches(nil, haystacks) {
+		t.Error("no patterns should never match")
+	}
+	// An invalid pattern is skipped, not fatal, and later valid patterns
+	// still get a chance to match.
+	if !retryOnMatches([]string{"(", "timeout"}, haystacks) {
+		t.Error("expected an invalid pattern to be skipped rather than abort the match")
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -47,10 +49,10 @@ type Stage struct {
 	NotBlocking bool `yaml:"not_blocking,omitempty" json:"not_blocking,omitempty"`
 
 	// RequireArtifacts is a name of the required artifacts. If the
-	// required artifact is missing (per the meta.json), the stage
-	// will not be executed. RequireArticts _implies_ sending builds/builds.json
-	// and builds/<BUILDID>/meta.json.
-	// TODO: IMPLEMENT
+	// required artifact is missing (per the meta.json) and is not built
+	// by another stage in this JobSpec, the stage will not be executed.
+	// RequireArticts _implies_ sending builds/builds.json
+	// and builds/<BUILDID>/meta.json. See BuildDAG in dag.go.
 	RequireArtifacts []string `yaml:"requires_artifacts,flow,omitempty" json:"requires_artifacts,omitempty"`
 
 	// BuildArtifacts produces "known" artifacts. The special "base"
@@ -70,9 +72,42 @@ type Stage struct {
 	// PostAlways ensures that the PostCommands are always run.
 	PostAlways bool `yaml:"post_always,omitempty" json:"post_always,omitempty"`
 
-	// ExecutionOrder is a number value that defines the order of stages. If two stages
-	// share the same execution order number, then they are allowed to run concurrently to each other.
-	ExecutionOrder int `yaml:"execution_order,omitempty" json:"execution_order,omitempty"`
+	// Runs is the number of times each generated command (BuildArtifacts
+	// and Commands, not Prep/PostCommands) is executed. A value <= 1
+	// means "run once", which is the historical behavior.
+	Runs int `yaml:"runs,omitempty" json:"runs,omitempty"`
+
+	// Threshold is the fraction of Runs that must succeed for the
+	// command to be considered successful. Defaults to 1.0, i.e. every
+	// run must pass. Useful for shaking out flaky live-iso/kola stages
+	// without treating every flake as a hard failure.
+	Threshold float64 `yaml:"threshold,omitempty" json:"threshold,omitempty"`
+
+	// RetryOn is a list of regular expressions matched against a failed
+	// run's error and captured output. When non-empty, only failures
+	// matching one of the patterns are retried; any other failure fails
+	// the command immediately, regardless of Threshold.
+	RetryOn []string `yaml:"retry_on,flow,omitempty" json:"retry_on,omitempty"`
+
+	// Timeout bounds the wall-clock time of the main command phase.
+	// PrepTimeout and PostTimeout bound the prep and post phases
+	// independently. Zero means unbounded, which is the historical
+	// behavior. On expiry the phase's context is canceled and execScript
+	// sends SIGTERM to the running script, escalating to SIGKILL after a
+	// short grace period (see killOrphan).
+	Timeout     time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	PrepTimeout time.Duration `yaml:"prep_timeout,omitempty" json:"prep_timeout,omitempty"`
+	PostTimeout time.Duration `yaml:"post_timeout,omitempty" json:"post_timeout,omitempty"`
+
+	// Matrix expands this stage into the Cartesian product of its axes,
+	// one derived Stage per combination, with `{{ .Matrix.<key> }}`
+	// substituted into Commands, PrepCommands, PostCommands,
+	// BuildArtifacts, and ID. See ExpandMatrix in matrix.go.
+	Matrix map[string][]string `yaml:"matrix,omitempty" json:"matrix,omitempty"`
+
+	// WithItems is shorthand for a single-axis Matrix: each item is
+	// substituted as `{{ .Item }}`.
+	WithItems []string `yaml:"with_items,flow,omitempty" json:"with_items,omitempty"`
 }
 
 // These are the only hard-coded commands that Gangplank understand.
@@ -131,8 +166,23 @@ func (s *Stage) getCommands(rd *RenderData) ([]string, error) {
 	return ret, nil
 }
 
-// Execute runs the commands of a stage.
-func (s *Stage) Execute(ctx context.Context, rd *RenderData, envVars []string) error {
+// Execute runs the commands of a stage. reporter is notified of the
+// stage's Started/Finished lifecycle and of each attempt's captured
+// output; pass nil to run silently (the historical, in-process-only
+// behavior), which is equivalent to passing NoopReporter.
+func (s *Stage) Execute(ctx context.Context, rd *RenderData, envVars []string, reporter StageReporter) (err error) {
+	if reporter == nil {
+		reporter = NoopReporter
+	}
+	reporter.Started(s.ID)
+	defer func() {
+		status := StageSucceeded
+		if err != nil {
+			status = StageFailed
+		}
+		reporter.Finished(s.ID, status, err)
+	}()
+
 	if ctx == nil {
 		return errors.New("context must not be nil")
 	}
@@ -164,8 +214,10 @@ func (s *Stage) Execute(ctx context.Context, rd *RenderData, envVars []string) e
 	if err := ioutil.WriteFile(prepScript, []byte(strings.Join(s.PrepCommands, "\n")), 0755); err != nil {
 		return err
 	}
-	if err := rd.RendererExecuter(ctx, envVars, prepScript); err != nil {
-		return fmt.Errorf("Failed execution of the prep stage: %w", err)
+	prepCtx, prepCancel := withPhaseTimeout(ctx, s.PrepTimeout)
+	defer prepCancel()
+	if err := execScript(prepCtx, rd, envVars, prepScript); err != nil {
+		return phaseErr("prep", s.PrepTimeout, prepCtx, err)
 	}
 
 	postScript := filepath.Join(tmpd, "post.sh")
@@ -175,10 +227,19 @@ func (s *Stage) Execute(ctx context.Context, rd *RenderData, envVars []string) e
 	if s.PostAlways {
 		log.Info("PostCommand will be executed regardless of command success")
 		defer func() {
-			_ = rd.RendererExecuter(ctx, envVars, postScript)
+			postCtx, postCancel := withPhaseTimeout(ctx, s.PostTimeout)
+			defer postCancel()
+			if err := execScript(postCtx, rd, envVars, postScript); err != nil {
+				log.WithError(phaseErr("post", s.PostTimeout, postCtx, err)).Error("post stage failed")
+			}
 		}()
 	}
 
+	// mainCtx bounds the entire main command phase (all attempts of all
+	// commands, serial or concurrent, share this one deadline).
+	mainCtx, mainCancel := withPhaseTimeout(ctx, s.Timeout)
+	defer mainCancel()
+
 	// Write out each command to their own file. To enable concurrent execution.
 	scripts := make(map[int]string)
 	for i, c := range cmds {
@@ -195,30 +256,32 @@ func (s *Stage) Execute(ctx context.Context, rd *RenderData, envVars []string) e
 		// Non-concurrent commands are run serially. Any failure will immediately
 		// break the run.
 		log.Infof("Executing %d stage commands serially", len(scripts))
-		for _, v := range scripts {
-			if err := rd.RendererExecuter(ctx, envVars, v); err != nil {
-				return err
+		for i, v := range scripts {
+			if err := s.runAttempts(mainCtx, rd, envVars, tmpd, i, v, reporter); err != nil {
+				return phaseErr("main", s.Timeout, mainCtx, err)
 			}
 		}
 	} else {
 		// Concurrent commands are run in parallel until all complete OR
-		// one fails.
+		// one fails. Each command's Runs/Threshold reliability is
+		// evaluated independently by runAttempts before its result is
+		// folded into the stage outcome.
 		log.Infof("Executing %d stage commands concurrently", len(scripts))
 		wg := &sync.WaitGroup{}
-		errors := make(chan error, len(scripts))
-		for _, s := range scripts {
+		errs := make(chan error, len(scripts))
+		for i, v := range scripts {
 			wg.Add(1)
-			go func(s string, w *sync.WaitGroup, ctx context.Context) {
+			go func(i int, scriptPath string, w *sync.WaitGroup, ctx context.Context) {
 				defer w.Done()
-				log.Infof("STARTING command: %s", s)
-				e := rd.RendererExecuter(ctx, envVars, s)
-				errors <- e
-				if err != nil {
-					log.Infof("ERROR %s", s)
+				log.Infof("STARTING command: %s", scriptPath)
+				e := s.runAttempts(ctx, rd, envVars, tmpd, i, scriptPath, reporter)
+				errs <- e
+				if e != nil {
+					log.Infof("ERROR %s", scriptPath)
 					return
 				}
-				log.Infof("SUCCESS %s", s)
-			}(s, wg, ctx)
+				log.Infof("SUCCESS %s", scriptPath)
+			}(i, v, wg, mainCtx)
 			// hack: ensure that scripts are started serially
 			//       but may run concurrently
 			time.Sleep(50 * time.Millisecond)
@@ -227,30 +290,210 @@ func (s *Stage) Execute(ctx context.Context, rd *RenderData, envVars []string) e
 		// Wait for the concurrent commands to run, and check
 		// all errors to make sure non are swallowed.
 		wg.Wait()
+		close(errs)
 		var e error = nil
-		for x := 0; x <= len(errors); x++ {
-			err, ok := <-errors
-			if !ok {
-				break
-			}
+		for err := range errs {
 			if err != nil {
 				log.Errorf("error recieved: %v", err)
 				e = err
 			}
 		}
 		if e != nil {
-			return e
+			return phaseErr("main", s.Timeout, mainCtx, e)
 		}
 	}
 
 	// If PostAlways, then the postScript is executed in defer call above.
 	if !s.PostAlways {
-		return rd.RendererExecuter(ctx, envVars, postScript)
+		postCtx, postCancel := withPhaseTimeout(ctx, s.PostTimeout)
+		defer postCancel()
+		if err := execScript(postCtx, rd, envVars, postScript); err != nil {
+			return phaseErr("post", s.PostTimeout, postCtx, err)
+		}
+	}
+
+	return nil
+}
+
+// withPhaseTimeout derives a child context bounded by timeout, unless
+// timeout is zero in which case the parent context is returned
+// unmodified (no bound). The returned cancel func is always safe to
+// defer, even when no child context was created.
+func withPhaseTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// phaseErr reports a phase's context as having timed out when its
+// deadline was exceeded, so that callers can distinguish a timeout from
+// an ordinary command failure instead of losing that detail in a bare
+// error string.
+func phaseErr(phase string, timeout time.Duration, phaseCtx context.Context, err error) error {
+	if phaseCtx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s phase exceeded its %s timeout: %w", phase, timeout, err)
+	}
+	return fmt.Errorf("%s phase failed: %w", phase, err)
+}
+
+// killGrace bounds how long killOrphan waits after SIGTERM before
+// escalating to SIGKILL.
+const killGrace = 5 * time.Second
+
+// killOrphan sends SIGTERM, then SIGKILL after killGrace, to any process
+// whose cmdline matches match. It's a backstop for when a phase's context
+// deadline fires: RendererExecuter's own ctx cancellation may already tear
+// its process tree down, but nothing here guarantees that, so execScript
+// calls this to make sure a timed-out script doesn't outlive its phase as
+// an orphan. match must identify the actual payload process -- not an
+// intermediate wrapper whose argv vanishes once it execs into the payload.
+func killOrphan(match string) {
+	if err := exec.Command("pkill", "-TERM", "-f", match).Run(); err != nil {
+		// No matching process, or pkill isn't available; either way
+		// there's nothing left to escalate to SIGKILL.
+		return
+	}
+	time.Sleep(killGrace)
+	_ = exec.Command("pkill", "-KILL", "-f", match).Run()
+}
+
+// execScript runs scriptPath via rd.RendererExecuter and, if ctx's
+// deadline is what ended it, calls killOrphan on it so a timed-out script
+// is actually terminated rather than just reported as failed.
+func execScript(ctx context.Context, rd *RenderData, envVars []string, scriptPath string) error {
+	return execScriptKillMatch(ctx, rd, envVars, scriptPath, scriptPath)
+}
+
+// execScriptKillMatch is execScript, but kills on killMatch instead of
+// scriptPath. Use this when scriptPath is itself a wrapper that execs
+// into the real payload (see runOneAttempt), so the pattern killOrphan
+// matches against is the one the OS actually schedules.
+func execScriptKillMatch(ctx context.Context, rd *RenderData, envVars []string, scriptPath, killMatch string) error {
+	err := rd.RendererExecuter(ctx, envVars, scriptPath)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		killOrphan(killMatch)
+	}
+	return err
+}
+
+// runAttempts executes a single generated command up to s.Runs times,
+// capturing each attempt's combined stdout/stderr into tmpd, and
+// declares the command successful only once the fraction of successful
+// attempts meets s.Threshold. idx identifies the command among its
+// siblings and is only used to namespace the per-attempt log files.
+func (s *Stage) runAttempts(ctx context.Context, rd *RenderData, envVars []string, tmpd string, idx int, scriptPath string, reporter StageReporter) error {
+	if reporter == nil {
+		reporter = NoopReporter
+	}
+	runs := s.Runs
+	if runs < 1 {
+		runs = 1
+	}
+	threshold := s.Threshold
+	if threshold <= 0 {
+		threshold = 1.0
+	}
+
+	l := log.WithFields(log.Fields{"script": scriptPath, "runs": runs, "threshold": threshold})
+
+	var lastErr error
+	successes := 0
+	for attempt := 1; attempt <= runs; attempt++ {
+		logPath := filepath.Join(tmpd, fmt.Sprintf("script-%d-attempt-%d.log", idx, attempt))
+		err := s.runOneAttempt(ctx, rd, envVars, scriptPath, logPath)
+		s.reportOutput(reporter, attempt, logPath)
+		al := l.WithField("attempt", attempt)
+		if err == nil {
+			successes++
+			al.Info("attempt succeeded")
+			continue
+		}
+		lastErr = err
+		al.WithError(err).Warn("attempt failed")
+
+		if len(s.RetryOn) > 0 && !s.matchesRetryOn(err, logPath) {
+			return fmt.Errorf("attempt %d/%d of %s failed with a non-retryable error: %w", attempt, runs, scriptPath, err)
+		}
 	}
 
+	ratio := float64(successes) / float64(runs)
+	l.WithFields(log.Fields{"successes": successes, "ratio": ratio}).Info("reliability threshold evaluated")
+	if !attemptsSatisfyThreshold(successes, runs, threshold) {
+		return fmt.Errorf("%s met only %d/%d (%.2f) successful runs, below threshold %.2f: %w",
+			scriptPath, successes, runs, ratio, threshold, lastErr)
+	}
 	return nil
 }
 
+// attemptsSatisfyThreshold reports whether successes out of runs attempts
+// clears threshold, e.g. 2 successes out of 3 runs (a 0.67 ratio) clears a
+// 0.5 threshold but not a 0.75 one.
+func attemptsSatisfyThreshold(successes, runs int, threshold float64) bool {
+	return float64(successes)/float64(runs) >= threshold
+}
+
+// runOneAttempt runs scriptPath once via the RenderData's executer,
+// redirecting its combined stdout/stderr to logPath so that PostCommands
+// (and matchesRetryOn) can inspect it.
+func (s *Stage) runOneAttempt(ctx context.Context, rd *RenderData, envVars []string, scriptPath, logPath string) error {
+	// exec replaces the wrapper process with the payload instead of
+	// forking it as a child, so there is exactly one process and its
+	// cmdline names scriptPath -- the thing killOrphan needs to match on
+	// timeout, not the wrapper file which stops existing as a process the
+	// moment exec runs.
+	wrapper := fmt.Sprintf("#!/bin/bash\nexec /bin/bash %q > %q 2>&1\n", scriptPath, logPath)
+	wrapperPath := logPath + ".sh"
+	if err := ioutil.WriteFile(wrapperPath, []byte(wrapper), 0755); err != nil {
+		return err
+	}
+	return execScriptKillMatch(ctx, rd, envVars, wrapperPath, scriptPath)
+}
+
+// reportOutput streams a completed attempt's captured log to reporter,
+// one line at a time, so a subscriber (e.g. the daemon) can tail it
+// live instead of waiting for the whole stage to finish.
+func (s *Stage) reportOutput(reporter StageReporter, attempt int, logPath string) {
+	out, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		reporter.Output(s.ID, attempt, line)
+	}
+}
+
+// matchesRetryOn reports whether a failed attempt's error or captured
+// output matches one of the stage's RetryOn patterns.
+func (s *Stage) matchesRetryOn(err error, logPath string) bool {
+	haystacks := []string{err.Error()}
+	if out, rerr := ioutil.ReadFile(logPath); rerr == nil {
+		haystacks = append(haystacks, string(out))
+	}
+	return retryOnMatches(s.RetryOn, haystacks)
+}
+
+// retryOnMatches reports whether any haystack matches one of patterns.
+// Invalid patterns are logged and skipped rather than failing the match.
+func retryOnMatches(patterns []string, haystacks []string) bool {
+	for _, pattern := range patterns {
+		re, cerr := regexp.Compile(pattern)
+		if cerr != nil {
+			log.WithError(cerr).WithField("pattern", pattern).Warn("invalid retry_on pattern, skipping")
+			continue
+		}
+		for _, h := range haystacks {
+			if re.MatchString(h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 var (
 	// pseudoStages are special setup and tear down phases.
 	pseudoStages = []string{"base", "finalize"}
@@ -263,6 +506,25 @@ func GetArtifactShortHandNames() []string {
 	return buildableArtifacts
 }
 
+// buildPriority orders the known artifacts relative to one another so
+// that, within a single generated stage, BuildArtifacts are emitted in
+// the order they must actually be built (e.g. "base" before "live-iso").
+// It has no bearing on how stages are scheduled relative to each other;
+// that is the DAG's job (see dag.go), driven purely by RequireArtifacts
+// and BuildArtifacts.
+func buildPriority(noun string) int {
+	switch noun {
+	case "base":
+		return 1
+	case "live-iso", "metal", "metal4k":
+		return 2
+	case "finalize":
+		return 999
+	default:
+		return 3
+	}
+}
+
 // addShorthandToStage adds in a build shorthand into the stage and
 // ensures that required dependencies are correclty ordered
 func addShorthandToStage(artifact string, stage *Stage) {
@@ -271,29 +533,24 @@ func addShorthandToStage(artifact string, stage *Stage) {
 		switch noun {
 		case "base":
 			return &Stage{
-				ExecutionOrder:   1,
 				BuildArtifacts:   []string{"base"},
 				RequireArtifacts: []string{"base"},
 			}
 		case "finalize":
 			return &Stage{
 				BuildArtifacts: []string{"finalize"},
-				ExecutionOrder: 999,
 			}
 		case "live-iso":
 			return &Stage{
-				ExecutionOrder:   2,
 				BuildArtifacts:   []string{"live-iso"},
 				RequireArtifacts: []string{"qemu", "metal", "metal4k"},
 			}
 		case "metal":
 			return &Stage{
-				ExecutionOrder: 2,
 				BuildArtifacts: []string{"metal"},
 			}
 		case "metal4k":
 			return &Stage{
-				ExecutionOrder: 2,
 				BuildArtifacts: []string{"metal4k"},
 			}
 		default:
@@ -301,7 +558,6 @@ func addShorthandToStage(artifact string, stage *Stage) {
 				break
 			}
 			return &Stage{
-				ExecutionOrder:   3,
 				BuildArtifacts:   []string{artifact},
 				RequireArtifacts: []string{"qemu"},
 			}
@@ -337,20 +593,14 @@ func addShorthandToStage(artifact string, stage *Stage) {
 	stage.BuildArtifacts = append(stage.BuildArtifacts, working.BuildArtifacts...)
 	stage.RequireArtifacts = append(stage.RequireArtifacts, working.RequireArtifacts...)
 
-	// Assume the lowest stage execution order
-	if working.ExecutionOrder < stage.ExecutionOrder || stage.ExecutionOrder == 0 {
-		stage.ExecutionOrder = working.ExecutionOrder
-	}
-
-	stage.ID = fmt.Sprintf("Generated Stage in Execution Order %d", stage.ExecutionOrder)
-	stage.Description = fmt.Sprintf("Stage %d execution for %s",
-		stage.ExecutionOrder, strings.Join(stage.BuildArtifacts, ","))
+	stage.ID = fmt.Sprintf("Generated Stage for %s", strings.Join(stage.BuildArtifacts, ","))
+	stage.Description = fmt.Sprintf("Stage execution for %s", strings.Join(stage.BuildArtifacts, ","))
 
 	// Get the order that artifacts should be built
 	artifactOrder := make(map[int][]string)
 	for _, v := range stage.BuildArtifacts {
-		fakeStage := quickStage(v)
-		artifactOrder[fakeStage.ExecutionOrder] = append(artifactOrder[fakeStage.ExecutionOrder], v)
+		p := buildPriority(v)
+		artifactOrder[p] = append(artifactOrder[p], v)
 	}
 
 	newOrder := []string{}
@@ -381,7 +631,10 @@ func addShorthandToStage(artifact string, stage *Stage) {
 	stage.RequireArtifacts = unique(realRequires)
 }
 
-// GenerateStages creates stages.
+// GenerateStages creates stages. Dependencies between the generated
+// stages are not computed here: they fall directly out of each
+// Stage's RequireArtifacts/BuildArtifacts and are resolved into a DAG
+// by BuildDAG at execution time.
 func (j *JobSpec) GenerateStages(fromNames []string) {
 	if len(fromNames) == 0 {
 		return